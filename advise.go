@@ -0,0 +1,65 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package mmap
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// Advice tells the kernel how the mapping is going to be accessed, see
+// madvise(2). Advices not known to the platform are reported as
+// ErrNotSupported.
+type Advice int
+
+type shouldAdvise interface {
+	DefaultAdvice() Advice
+}
+
+func (m *Mmap) defaultAdvice() Advice {
+	if args, ok := m.args.(shouldAdvise); ok {
+		return args.DefaultAdvice()
+	}
+	return AdviceNormal
+}
+
+// destructive reports whether advice may drop the contents of a private or
+// anonymous mapping, which rules it out as the default advice
+func (a Advice) destructive() bool {
+	return a == AdviceDontNeed || a == AdviceFree
+}
+
+func (m *Mmap) Advise(advice Advice) error {
+	if err := m.rlock(0); err != nil {
+		return err
+	}
+	defer m.mu.RUnlock()
+
+	return m.advise(0, len(m.data), advice)
+}
+
+// AdviseRange applies advice to [off, off+length), extending the range down
+// to the page boundary.
+func (m *Mmap) AdviseRange(off int64, length int, advice Advice) error {
+	if err := m.rlock(0); err != nil {
+		return err
+	}
+	defer m.mu.RUnlock()
+
+	if off < 0 || length < 0 || off+int64(length) > int64(len(m.data)) {
+		return ErrOverflow
+	}
+
+	return m.advise(int(off), int(off)+length, advice)
+}
+
+func (m *Mmap) advise(start, end int, advice Advice) error {
+	if advice < 0 {
+		return ErrNotSupported
+	}
+	if start == end {
+		return nil
+	}
+
+	return unix.Madvise(m.pages(start, end), int(advice))
+}
@@ -0,0 +1,14 @@
+package mmap
+
+import "golang.org/x/sys/unix"
+
+const (
+	AdviceNormal     Advice = unix.MADV_NORMAL
+	AdviceSequential Advice = unix.MADV_SEQUENTIAL
+	AdviceRandom     Advice = unix.MADV_RANDOM
+	AdviceWillNeed   Advice = unix.MADV_WILLNEED
+	AdviceDontNeed   Advice = unix.MADV_DONTNEED
+	AdviceHugePage   Advice = -1
+	AdviceNoHugePage Advice = -2
+	AdviceFree       Advice = unix.MADV_FREE
+)
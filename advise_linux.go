@@ -0,0 +1,14 @@
+package mmap
+
+import "golang.org/x/sys/unix"
+
+const (
+	AdviceNormal     Advice = unix.MADV_NORMAL
+	AdviceSequential Advice = unix.MADV_SEQUENTIAL
+	AdviceRandom     Advice = unix.MADV_RANDOM
+	AdviceWillNeed   Advice = unix.MADV_WILLNEED
+	AdviceDontNeed   Advice = unix.MADV_DONTNEED
+	AdviceHugePage   Advice = unix.MADV_HUGEPAGE
+	AdviceNoHugePage Advice = unix.MADV_NOHUGEPAGE
+	AdviceFree       Advice = unix.MADV_FREE
+)
@@ -0,0 +1,71 @@
+package mmap
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ImSingee/tt"
+)
+
+func TestAdviceSurvivesGrowth(t *testing.T) {
+	for name, mremap := range map[string]bool{"mremap": true, "remap": false} {
+		t.Run(name, func(t *testing.T) {
+			defer func(old bool) { canMremap = old }(canMremap)
+			canMremap = mremap
+
+			args := NewReadWrite("")
+			args.Advice = AdviceRandom
+
+			mmap, err := New(args)
+			tt.AssertIsNotError(t, err)
+			defer closeMmap(t, mmap)
+
+			tt.AssertTrue(t, hasVmFlag(t, mmap.data, "rr"))
+
+			_, err = mmap.WriteAt([]byte(HelloWorld), 4*oneMB)
+			tt.AssertIsNotError(t, err)
+			tt.AssertTrue(t, hasVmFlag(t, mmap.data, "rr"))
+
+			tt.AssertIsNotError(t, mmap.Advise(AdviceSequential))
+			tt.AssertTrue(t, hasVmFlag(t, mmap.data, "sr"))
+		})
+	}
+}
+
+// hasVmFlag looks up the VmFlags of the mapping holding b in /proc/self/smaps
+func hasVmFlag(t *testing.T, b []byte, flag string) bool {
+	t.Helper()
+
+	f, err := os.Open("/proc/self/smaps")
+	tt.AssertIsNotError(t, err)
+	defer f.Close()
+
+	addr := addrOf(b)
+	found := false
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := s.Text()
+
+		var start, end uintptr
+		if n, _ := fmt.Sscanf(line, "%x-%x", &start, &end); n == 2 {
+			found = start <= addr && addr < end
+			continue
+		}
+
+		if found && strings.HasPrefix(line, "VmFlags:") {
+			for _, f := range strings.Fields(line)[1:] {
+				if f == flag {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	t.Fatal("mapping not found in /proc/self/smaps")
+	return false
+}
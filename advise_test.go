@@ -0,0 +1,87 @@
+package mmap
+
+import (
+	"testing"
+
+	"github.com/ImSingee/tt"
+)
+
+func TestAdvise(t *testing.T) {
+	t.Run("simple", func(t *testing.T) {
+		mmap, err := New(NewReadWrite(""))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		tt.AssertIsNotError(t, mmap.Advise(AdviceSequential))
+		tt.AssertIsNotError(t, mmap.Advise(AdviceNormal))
+		tt.AssertIsNotError(t, mmap.AdviseRange(5, 3*pageSize, AdviceWillNeed))
+		tt.AssertIsNotError(t, mmap.AdviseRange(0, 0, AdviceRandom))
+	})
+
+	t.Run("dont-need", func(t *testing.T) {
+		mmap, err := New(NewReadWrite(""))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		_, err = mmap.WriteAt([]byte(HelloWorld), 0)
+		tt.AssertIsNotError(t, err)
+
+		// shared file pages are still backed by the file
+		tt.AssertIsNotError(t, mmap.Advise(AdviceDontNeed))
+
+		p, err := mmap.Bytes(0, LenOfHelloWorld)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, HelloWorld, string(p))
+	})
+
+	t.Run("default-advice", func(t *testing.T) {
+		args := NewReadWrite("")
+		args.Advice = AdviceRandom
+
+		mmap, err := New(args)
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		_, err = mmap.WriteAt([]byte(HelloWorld), 4*oneMB)
+		tt.AssertIsNotError(t, err)
+	})
+
+	t.Run("destructive-default-advice", func(t *testing.T) {
+		for _, advice := range []Advice{AdviceDontNeed, AdviceFree} {
+			args := NewAnonymous(oneMB)
+			args.Advice = advice
+
+			mmap, err := New(args)
+			tt.AssertEqual(t, ErrNotSupported, err)
+			tt.AssertTrue(t, mmap.IsClosed())
+
+			rw := NewReadWrite("")
+			rw.Private = true
+			rw.Advice = advice
+
+			_, err = New(rw)
+			tt.AssertEqual(t, ErrNotSupported, err)
+		}
+	})
+
+	t.Run("overflow", func(t *testing.T) {
+		f, err := newHelloWorldFile()
+		tt.AssertIsNotError(t, err)
+
+		mmap, err := New(NewReadOnly(f))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		tt.AssertEqual(t, ErrOverflow, mmap.AdviseRange(0, LenOfHelloWorld+1, AdviceRandom))
+		tt.AssertEqual(t, ErrOverflow, mmap.AdviseRange(-1, 1, AdviceRandom))
+	})
+
+	t.Run("advise-after-close", func(t *testing.T) {
+		mmap, err := New(NewReadWrite(""))
+		tt.AssertIsNotError(t, err)
+		closeMmap(t, mmap)
+
+		tt.AssertEqual(t, ErrIsClosed, mmap.Advise(AdviceRandom))
+		tt.AssertEqual(t, ErrIsClosed, mmap.AdviseRange(0, 1, AdviceRandom))
+	})
+}
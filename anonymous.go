@@ -0,0 +1,108 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package mmap
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// Anonymous is an Opener for a mapping that is not backed by any file, e.g.
+// a large off-heap buffer. It supports the same growth as file mappings.
+//
+// A Shared mapping stays shared with child processes forked from then on.
+// Growth copies it into a new mapping which the children do not see, unless
+// it happens inside the address space reserved with ReserveSize, where the
+// existing pages are left in place. ReserveSize is only supported on Linux,
+// see Args.ReserveSize.
+type Anonymous struct {
+	InitLength int
+	Shared     bool
+
+	ReserveSize int
+	Advice      Advice
+	Locked      bool
+}
+
+var _ Opener = (*Anonymous)(nil)
+var _ shouldClean = (*Anonymous)(nil)
+var _ shouldReserve = (*Anonymous)(nil)
+var _ shouldAdvise = (*Anonymous)(nil)
+var _ shouldLock = (*Anonymous)(nil)
+
+func NewAnonymous(size int) *Anonymous {
+	return &Anonymous{
+		InitLength: size,
+	}
+}
+
+func (a *Anonymous) Clean() error {
+	if a.InitLength <= 0 {
+		a.InitLength = DefaultInitLength
+	}
+	return nil
+}
+
+// Open returns no file, the mapping is anonymous.
+func (a *Anonymous) Open() (*os.File, error) {
+	return nil, nil
+}
+
+func (a *Anonymous) Offset() int64 {
+	return 0
+}
+
+func (a *Anonymous) InitialSize() int {
+	return a.InitLength
+}
+
+func (a *Anonymous) Prot() int {
+	return unix.PROT_READ | unix.PROT_WRITE
+}
+
+func (a *Anonymous) Flags() int {
+	if a.Shared {
+		return unix.MAP_SHARED | unix.MAP_ANON
+	} else {
+		return unix.MAP_PRIVATE | unix.MAP_ANON
+	}
+}
+
+func (a *Anonymous) ReservedSize() int {
+	return a.ReserveSize
+}
+
+func (a *Anonymous) DefaultAdvice() Advice {
+	return a.Advice
+}
+
+func (a *Anonymous) ShouldLock() (locked bool, onFault bool) {
+	return a.Locked, false
+}
+
+func (m *Mmap) anonymous() bool {
+	return m.args.Flags()&unix.MAP_ANON != 0
+}
+
+// reallocate resizes an anonymous mapping by copying it into a new one,
+// there is no file to map again.
+func (m *Mmap) reallocate(newCap int) error {
+	mapping, err := sysMmap(0, m.slack+newCap, m.args.Prot(), m.args.Flags(), -1, 0)
+	if err != nil {
+		return err
+	}
+
+	copy(mapping[m.slack:], m.data)
+	if m.mapping != nil {
+		if err := sysMunmap(m.mapping); err != nil {
+			_ = sysMunmap(mapping)
+			return err
+		}
+	}
+
+	m.mapping = mapping
+	m.setData(mapping, newCap)
+	return nil
+}
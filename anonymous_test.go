@@ -0,0 +1,101 @@
+package mmap
+
+import (
+	"bytes"
+	"io"
+	"runtime"
+	"testing"
+
+	"github.com/ImSingee/tt"
+)
+
+func TestAnonymous(t *testing.T) {
+	for name, args := range map[string]*Anonymous{
+		"private": {},
+		"shared":  {Shared: true},
+	} {
+		args := args
+
+		t.Run(name, func(t *testing.T) {
+			mmap, err := New(args)
+			tt.AssertIsNotError(t, err)
+			defer closeMmap(t, mmap)
+
+			tt.AssertEqual(t, DefaultInitLength, mmap.Cap())
+			tt.AssertEqual(t, 0, mmap.Len())
+
+			_, err = mmap.WriteAt([]byte(HelloWorld), 0)
+			tt.AssertIsNotError(t, err)
+			_, err = mmap.WriteAt([]byte(HelloWorld), 4*oneMB)
+			tt.AssertIsNotError(t, err)
+			tt.AssertEqual(t, 5*oneMB, mmap.Cap())
+			tt.AssertEqual(t, 4*oneMB+LenOfHelloWorld, mmap.Len())
+
+			p, err := mmap.Bytes(0, LenOfHelloWorld)
+			tt.AssertIsNotError(t, err)
+			tt.AssertEqual(t, HelloWorld, string(p))
+
+			p, err = mmap.Bytes(4*oneMB, 2*LenOfHelloWorld)
+			tt.AssertEqual(t, io.EOF, err)
+			tt.AssertEqual(t, HelloWorld, string(p))
+
+			tt.AssertIsNotError(t, mmap.Flush())
+			tt.AssertIsNotError(t, mmap.Truncate(5))
+			tt.AssertEqual(t, 5, mmap.Cap())
+
+			buf := &bytes.Buffer{}
+			_, err = mmap.WriteTo(buf)
+			tt.AssertIsNotError(t, err)
+			tt.AssertEqual(t, "Hello", buf.String())
+		})
+	}
+}
+
+func TestAnonymousWithoutMremap(t *testing.T) {
+	defer func(old bool) { canMremap = old }(canMremap)
+	canMremap = false
+
+	mmap, err := New(NewAnonymous(oneMB))
+	tt.AssertIsNotError(t, err)
+	defer closeMmap(t, mmap)
+
+	_, err = mmap.WriteAt([]byte(HelloWorld), 0)
+	tt.AssertIsNotError(t, err)
+	_, err = mmap.WriteAt([]byte(HelloWorld), 2*oneMB)
+	tt.AssertIsNotError(t, err)
+	tt.AssertEqual(t, 3*oneMB, mmap.Cap())
+
+	p, err := mmap.Bytes(0, LenOfHelloWorld)
+	tt.AssertIsNotError(t, err)
+	tt.AssertEqual(t, HelloWorld, string(p))
+
+	tt.AssertIsNotError(t, mmap.Truncate(0))
+	tt.AssertEqual(t, 0, mmap.Cap())
+
+	_, err = mmap.WriteAt([]byte(HelloWorld), 0)
+	tt.AssertIsNotError(t, err)
+	p, err = mmap.Bytes(0, LenOfHelloWorld)
+	tt.AssertIsNotError(t, err)
+	tt.AssertEqual(t, HelloWorld, string(p))
+}
+
+func TestAnonymousReserved(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("ReserveSize is only supported on linux")
+	}
+
+	mmap, err := New(&Anonymous{InitLength: oneMB, ReserveSize: oneGB})
+	tt.AssertIsNotError(t, err)
+	defer closeMmap(t, mmap)
+
+	base := &mmap.data[0]
+	_, err = mmap.WriteAt([]byte(HelloWorld), 0)
+	tt.AssertIsNotError(t, err)
+	_, err = mmap.WriteAt([]byte(HelloWorld), 100*oneMB)
+	tt.AssertIsNotError(t, err)
+	tt.AssertTrue(t, base == &mmap.data[0])
+
+	p, err := mmap.Bytes(0, LenOfHelloWorld)
+	tt.AssertIsNotError(t, err)
+	tt.AssertEqual(t, HelloWorld, string(p))
+}
@@ -7,6 +7,8 @@ import (
 	"golang.org/x/sys/unix"
 )
 
+// Opener describes what to map. Open may return a nil file together with
+// MAP_ANON in Flags for an anonymous mapping, see Anonymous.
 type Opener interface {
 	Open() (*os.File, error)
 	Offset() int64
@@ -19,15 +21,61 @@ type shouldClean interface {
 	Clean() error
 }
 
+type shouldSyncMetadata interface {
+	ShouldSyncMetadata() bool
+}
+
 type Args struct {
-	File       string
+	File string
+
+	// FileOffset and InitLength select the window [FileOffset,
+	// FileOffset+InitLength) of the file to map, all offsets used with the
+	// Mmap are relative to FileOffset. It needs not be page aligned.
+	FileOffset int64
 	InitLength int
-	Readonly   bool
-	Private    bool
+
+	Readonly bool
+	Private  bool
+
+	// SyncMetadata makes every synchronous flush also fdatasync the file
+	SyncMetadata bool
+
+	// ReserveSize reserves this much address space up front, growth then
+	// happens in place and the mapping never moves until it is exhausted. It
+	// is only supported on Linux, darwin cannot place mappings and New
+	// returns ErrNotSupported.
+	ReserveSize int
+
+	// Advice is applied to the mapping when it is created and after every
+	// remap. AdviceDontNeed and AdviceFree would drop what was written on
+	// every growth, New rejects them with ErrNotSupported.
+	Advice Advice
+
+	// Locked pins the mapping in RAM, see Mmap.Lock. With LockOnFault pages
+	// are only pinned once touched, see Mmap.LockOnFault.
+	Locked      bool
+	LockOnFault bool
+
+	// TrimOnClose truncates the file to Len on Close, dropping the zeroed
+	// capacity that growth added behind it. Only set it if no other map of
+	// the file, in this or another process, is still in use then, they would
+	// lose what they wrote behind Len and fault on their next access.
+	TrimOnClose bool
+
+	// SharedHeader keeps the capacity and a generation in a page in front of
+	// the window, so that growth in one process is picked up by every other
+	// process mapping the file with SharedHeader, see Mmap.Refresh.
+	SharedHeader bool
 }
 
 var _ Opener = (*Args)(nil)
 var _ shouldClean = (*Args)(nil)
+var _ shouldSyncMetadata = (*Args)(nil)
+var _ shouldReserve = (*Args)(nil)
+var _ shouldAdvise = (*Args)(nil)
+var _ shouldLock = (*Args)(nil)
+var _ shouldShareHeader = (*Args)(nil)
+var _ shouldTrimOnClose = (*Args)(nil)
 
 const DefaultInitLength = oneMB
 
@@ -50,7 +98,13 @@ func (a *Args) Clean() error {
 		if err != nil {
 			return err
 		}
-		a.InitLength = int(n.Size())
+		a.InitLength = int(n.Size() - a.FileOffset)
+		if a.SharedHeader {
+			a.InitLength -= sharedHeaderSize
+		}
+		if a.InitLength < 0 {
+			a.InitLength = 0
+		}
 	}
 
 	return nil
@@ -65,7 +119,7 @@ func (a *Args) Open() (*os.File, error) {
 }
 
 func (a *Args) Offset() int64 {
-	return 0
+	return a.FileOffset
 }
 
 func (a *Args) InitialSize() int {
@@ -88,6 +142,22 @@ func (a *Args) Flags() int {
 	}
 }
 
+func (a *Args) ShouldSyncMetadata() bool {
+	return a.SyncMetadata
+}
+
+func (a *Args) ReservedSize() int {
+	return a.ReserveSize
+}
+
+func (a *Args) DefaultAdvice() Advice {
+	return a.Advice
+}
+
+func (a *Args) ShouldLock() (locked bool, onFault bool) {
+	return a.Locked, a.LockOnFault
+}
+
 func NewReadOnly(file string) *Args {
 	return &Args{
 		File:       file,
@@ -105,3 +175,11 @@ func NewReadWrite(file string) *Args {
 		Private:    false,
 	}
 }
+
+func (a *Args) ShouldShareHeader() bool {
+	return a.SharedHeader
+}
+
+func (a *Args) ShouldTrimOnClose() bool {
+	return a.TrimOnClose
+}
@@ -0,0 +1,163 @@
+package mmap
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// The atomic operations work on words within Cap, so that processes sharing
+// the file see each other's updates. They hold mu for reading, so growth waits
+// for them and never moves a word in the middle of an operation. Every word
+// must be naturally aligned in memory, otherwise ErrUnaligned is returned.
+
+// word read-locks the map and returns the size byte word at off. The caller
+// must m.mu.RUnlock on success.
+func (m *Mmap) word(off int64, size int) (unsafe.Pointer, error) {
+	if err := m.rlock(0); err != nil {
+		return nil, err
+	}
+
+	if off < 0 || off > int64(len(m.data)-size) {
+		m.mu.RUnlock()
+		return nil, ErrOverflow
+	}
+
+	p := unsafe.Pointer(&m.data[off])
+	if uintptr(p)%uintptr(size) != 0 {
+		m.mu.RUnlock()
+		return nil, ErrUnaligned
+	}
+
+	return p, nil
+}
+
+// writableWord is word for the operations that store to the word, on a map
+// that cannot be written they return ErrReadOnly rather than fault.
+func (m *Mmap) writableWord(off int64, size int) (unsafe.Pointer, error) {
+	if m.readOnly() {
+		return nil, ErrReadOnly
+	}
+	return m.word(off, size)
+}
+
+func (m *Mmap) AtomicLoadUint32(off int64) (uint32, error) {
+	p, err := m.word(off, 4)
+	if err != nil {
+		return 0, err
+	}
+	defer m.mu.RUnlock()
+
+	return atomic.LoadUint32((*uint32)(p)), nil
+}
+
+func (m *Mmap) AtomicLoadUint64(off int64) (uint64, error) {
+	p, err := m.word(off, 8)
+	if err != nil {
+		return 0, err
+	}
+	defer m.mu.RUnlock()
+
+	return atomic.LoadUint64((*uint64)(p)), nil
+}
+
+func (m *Mmap) AtomicStoreUint32(off int64, v uint32) error {
+	p, err := m.writableWord(off, 4)
+	if err != nil {
+		return err
+	}
+	defer m.mu.RUnlock()
+
+	atomic.StoreUint32((*uint32)(p), v)
+	m.extend(int(off) + 4)
+	return nil
+}
+
+func (m *Mmap) AtomicStoreUint64(off int64, v uint64) error {
+	p, err := m.writableWord(off, 8)
+	if err != nil {
+		return err
+	}
+	defer m.mu.RUnlock()
+
+	atomic.StoreUint64((*uint64)(p), v)
+	m.extend(int(off) + 8)
+	return nil
+}
+
+// AtomicAddUint32 adds delta to the word at off and returns the new value.
+func (m *Mmap) AtomicAddUint32(off int64, delta uint32) (uint32, error) {
+	p, err := m.writableWord(off, 4)
+	if err != nil {
+		return 0, err
+	}
+	defer m.mu.RUnlock()
+
+	v := atomic.AddUint32((*uint32)(p), delta)
+	m.extend(int(off) + 4)
+	return v, nil
+}
+
+// AtomicAddUint64 adds delta to the word at off and returns the new value.
+func (m *Mmap) AtomicAddUint64(off int64, delta uint64) (uint64, error) {
+	p, err := m.writableWord(off, 8)
+	if err != nil {
+		return 0, err
+	}
+	defer m.mu.RUnlock()
+
+	v := atomic.AddUint64((*uint64)(p), delta)
+	m.extend(int(off) + 8)
+	return v, nil
+}
+
+// AtomicSwapUint32 stores v to the word at off and returns the old value.
+func (m *Mmap) AtomicSwapUint32(off int64, v uint32) (uint32, error) {
+	p, err := m.writableWord(off, 4)
+	if err != nil {
+		return 0, err
+	}
+	defer m.mu.RUnlock()
+
+	old := atomic.SwapUint32((*uint32)(p), v)
+	m.extend(int(off) + 4)
+	return old, nil
+}
+
+// AtomicSwapUint64 stores v to the word at off and returns the old value.
+func (m *Mmap) AtomicSwapUint64(off int64, v uint64) (uint64, error) {
+	p, err := m.writableWord(off, 8)
+	if err != nil {
+		return 0, err
+	}
+	defer m.mu.RUnlock()
+
+	old := atomic.SwapUint64((*uint64)(p), v)
+	m.extend(int(off) + 8)
+	return old, nil
+}
+
+func (m *Mmap) CompareAndSwapUint32(off int64, old, new uint32) (swapped bool, err error) {
+	p, err := m.writableWord(off, 4)
+	if err != nil {
+		return false, err
+	}
+	defer m.mu.RUnlock()
+
+	if swapped = atomic.CompareAndSwapUint32((*uint32)(p), old, new); swapped {
+		m.extend(int(off) + 4)
+	}
+	return swapped, nil
+}
+
+func (m *Mmap) CompareAndSwapUint64(off int64, old, new uint64) (swapped bool, err error) {
+	p, err := m.writableWord(off, 8)
+	if err != nil {
+		return false, err
+	}
+	defer m.mu.RUnlock()
+
+	if swapped = atomic.CompareAndSwapUint64((*uint64)(p), old, new); swapped {
+		m.extend(int(off) + 8)
+	}
+	return swapped, nil
+}
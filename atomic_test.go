@@ -0,0 +1,178 @@
+package mmap
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ImSingee/tt"
+)
+
+func TestAtomic(t *testing.T) {
+	t.Run("ops", func(t *testing.T) {
+		mmap, err := New(NewReadWrite(""))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		tt.AssertIsNotError(t, mmap.AtomicStoreUint32(4, 7))
+		v32, err := mmap.AtomicAddUint32(4, 3)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, uint32(10), v32)
+
+		old32, err := mmap.AtomicSwapUint32(4, 1)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, uint32(10), old32)
+
+		swapped, err := mmap.CompareAndSwapUint32(4, 2, 3)
+		tt.AssertIsNotError(t, err)
+		tt.AssertFalse(t, swapped)
+		swapped, err = mmap.CompareAndSwapUint32(4, 1, 3)
+		tt.AssertIsNotError(t, err)
+		tt.AssertTrue(t, swapped)
+
+		v32, err = mmap.AtomicLoadUint32(4)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, uint32(3), v32)
+
+		tt.AssertIsNotError(t, mmap.AtomicStoreUint64(16, 1<<40))
+		v64, err := mmap.AtomicAddUint64(16, ^uint64(0))
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, uint64(1<<40-1), v64)
+
+		old64, err := mmap.AtomicSwapUint64(16, 5)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, uint64(1<<40-1), old64)
+
+		swapped, err = mmap.CompareAndSwapUint64(16, 5, 6)
+		tt.AssertIsNotError(t, err)
+		tt.AssertTrue(t, swapped)
+
+		v64, err = mmap.AtomicLoadUint64(16)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, uint64(6), v64)
+
+		tt.AssertEqual(t, 24, mmap.Len())
+	})
+
+	t.Run("bounds", func(t *testing.T) {
+		mmap, err := New(NewReadWrite(""))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		// within Cap although beyond Len
+		_, err = mmap.AtomicLoadUint64(int64(mmap.Cap() - 8))
+		tt.AssertIsNotError(t, err)
+
+		_, err = mmap.AtomicLoadUint64(int64(mmap.Cap()))
+		tt.AssertEqual(t, ErrOverflow, err)
+		_, err = mmap.AtomicAddUint32(-4, 1)
+		tt.AssertEqual(t, ErrOverflow, err)
+
+		_, err = mmap.AtomicLoadUint64(4)
+		tt.AssertEqual(t, ErrUnaligned, err)
+		tt.AssertEqual(t, ErrUnaligned, mmap.AtomicStoreUint32(2, 1))
+		tt.AssertEqual(t, 0, mmap.Len())
+	})
+
+	t.Run("unaligned-window", func(t *testing.T) {
+		f, err := newHelloWorldFile()
+		tt.AssertIsNotError(t, err)
+
+		args := NewReadWrite(f)
+		args.FileOffset = 2
+
+		mmap, err := New(args)
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		// alignment is that of the address, not of the offset
+		_, err = mmap.AtomicLoadUint32(0)
+		tt.AssertEqual(t, ErrUnaligned, err)
+		_, err = mmap.AtomicLoadUint32(2)
+		tt.AssertIsNotError(t, err)
+	})
+
+	t.Run("shared", func(t *testing.T) {
+		a, err := New(NewReadWrite(""))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, a)
+
+		b, err := New(NewReadWrite(a.args.(*Args).File))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, b)
+
+		_, err = a.AtomicAddUint64(0, 2)
+		tt.AssertIsNotError(t, err)
+		_, err = b.AtomicAddUint64(0, 3)
+		tt.AssertIsNotError(t, err)
+
+		v, err := a.AtomicLoadUint64(0)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, uint64(5), v)
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		mmap, err := New(NewReadWrite(""))
+		tt.AssertIsNotError(t, err)
+		closeMmap(t, mmap)
+
+		_, err = mmap.AtomicLoadUint32(0)
+		tt.AssertEqual(t, ErrIsClosed, err)
+	})
+
+	t.Run("concurrent-growth", func(t *testing.T) {
+		mmap, err := New(NewReadWrite(""))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		const adders = 8
+		const perAdder = 10000
+
+		var wg sync.WaitGroup
+		for i := 0; i < adders; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < perAdder; j++ {
+					if _, err := mmap.AtomicAddUint64(8, 1); err != nil {
+						t.Error(err)
+						return
+					}
+				}
+			}()
+		}
+
+		for i := 1; i <= 8; i++ {
+			_, err := mmap.WriteAt([]byte{1}, int64(i*oneMB))
+			tt.AssertIsNotError(t, err)
+		}
+		wg.Wait()
+
+		v, err := mmap.AtomicLoadUint64(8)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, uint64(adders*perAdder), v)
+	})
+
+	t.Run("read-only", func(t *testing.T) {
+		f, err := newHelloWorldFile()
+		tt.AssertIsNotError(t, err)
+
+		mmap, err := New(NewReadOnly(f))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		_, err = mmap.AtomicLoadUint32(4)
+		tt.AssertIsNotError(t, err)
+
+		tt.AssertEqual(t, ErrReadOnly, mmap.AtomicStoreUint32(4, 1))
+		_, err = mmap.AtomicAddUint32(4, 1)
+		tt.AssertEqual(t, ErrReadOnly, err)
+		_, err = mmap.AtomicSwapUint64(0, 1)
+		tt.AssertEqual(t, ErrReadOnly, err)
+		_, err = mmap.CompareAndSwapUint32(0, 0, 1)
+		tt.AssertEqual(t, ErrReadOnly, err)
+
+		p, err := mmap.Bytes(0, LenOfHelloWorld)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, HelloWorld, string(p))
+	})
+}
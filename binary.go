@@ -0,0 +1,189 @@
+package mmap
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// view read-locks the map and returns the size bytes at off, which must lie
+// within Len. The caller must m.mu.RUnlock on success.
+func (m *Mmap) view(off int64, size int) ([]byte, error) {
+	if err := m.rlock(0); err != nil {
+		return nil, err
+	}
+
+	if off < 0 || off > int64(m.Len())-int64(size) {
+		m.mu.RUnlock()
+		return nil, ErrOverflow
+	}
+
+	return m.data[off : off+int64(size)], nil
+}
+
+// span read-locks the map, growing it like WriteAt, and returns the size bytes
+// at off to be written. The caller must m.extend and m.mu.RUnlock on success.
+func (m *Mmap) span(off int64, size int) ([]byte, error) {
+	if off < 0 {
+		return nil, ErrOverflow
+	}
+	if m.readOnly() {
+		return nil, ErrReadOnly
+	}
+
+	end := int(off) + size
+	if err := m.rlock(end); err != nil {
+		return nil, err
+	}
+
+	return m.data[off:end], nil
+}
+
+func (m *Mmap) Uint16At(off int64, order binary.ByteOrder) (uint16, error) {
+	p, err := m.view(off, 2)
+	if err != nil {
+		return 0, err
+	}
+	defer m.mu.RUnlock()
+
+	return order.Uint16(p), nil
+}
+
+func (m *Mmap) Uint32At(off int64, order binary.ByteOrder) (uint32, error) {
+	p, err := m.view(off, 4)
+	if err != nil {
+		return 0, err
+	}
+	defer m.mu.RUnlock()
+
+	return order.Uint32(p), nil
+}
+
+func (m *Mmap) Uint64At(off int64, order binary.ByteOrder) (uint64, error) {
+	p, err := m.view(off, 8)
+	if err != nil {
+		return 0, err
+	}
+	defer m.mu.RUnlock()
+
+	return order.Uint64(p), nil
+}
+
+func (m *Mmap) Float32At(off int64, order binary.ByteOrder) (float32, error) {
+	v, err := m.Uint32At(off, order)
+	return math.Float32frombits(v), err
+}
+
+func (m *Mmap) Float64At(off int64, order binary.ByteOrder) (float64, error) {
+	v, err := m.Uint64At(off, order)
+	return math.Float64frombits(v), err
+}
+
+// UvarintAt decodes the uvarint at off and returns it with the number of bytes
+// it takes. A truncated or overlong uvarint is ErrOverflow.
+func (m *Mmap) UvarintAt(off int64) (v uint64, n int, err error) {
+	if err = m.rlock(0); err != nil {
+		return 0, 0, err
+	}
+	defer m.mu.RUnlock()
+
+	length := int64(m.Len())
+	if off < 0 || off >= length {
+		return 0, 0, ErrOverflow
+	}
+
+	end := off + binary.MaxVarintLen64
+	if end > length {
+		end = length
+	}
+
+	if v, n = binary.Uvarint(m.data[off:end]); n <= 0 {
+		return 0, 0, ErrOverflow
+	}
+	return v, n, nil
+}
+
+// VarintAt decodes the varint at off, like UvarintAt.
+func (m *Mmap) VarintAt(off int64) (v int64, n int, err error) {
+	ux, n, err := m.UvarintAt(off)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// the zig-zag decoding of binary.Varint
+	v = int64(ux >> 1)
+	if ux&1 != 0 {
+		v = ^v
+	}
+	return v, n, nil
+}
+
+func (m *Mmap) PutUint16At(off int64, v uint16, order binary.ByteOrder) error {
+	p, err := m.span(off, 2)
+	if err != nil {
+		return err
+	}
+	defer m.mu.RUnlock()
+
+	order.PutUint16(p, v)
+	m.extend(int(off) + 2)
+	return nil
+}
+
+func (m *Mmap) PutUint32At(off int64, v uint32, order binary.ByteOrder) error {
+	p, err := m.span(off, 4)
+	if err != nil {
+		return err
+	}
+	defer m.mu.RUnlock()
+
+	order.PutUint32(p, v)
+	m.extend(int(off) + 4)
+	return nil
+}
+
+func (m *Mmap) PutUint64At(off int64, v uint64, order binary.ByteOrder) error {
+	p, err := m.span(off, 8)
+	if err != nil {
+		return err
+	}
+	defer m.mu.RUnlock()
+
+	order.PutUint64(p, v)
+	m.extend(int(off) + 8)
+	return nil
+}
+
+func (m *Mmap) PutFloat32At(off int64, v float32, order binary.ByteOrder) error {
+	return m.PutUint32At(off, math.Float32bits(v), order)
+}
+
+func (m *Mmap) PutFloat64At(off int64, v float64, order binary.ByteOrder) error {
+	return m.PutUint64At(off, math.Float64bits(v), order)
+}
+
+// PutUvarintAt encodes v as uvarint at off and returns the number of bytes
+// written.
+func (m *Mmap) PutUvarintAt(off int64, v uint64) (n int, err error) {
+	var buf [binary.MaxVarintLen64]byte
+	n = binary.PutUvarint(buf[:], v)
+
+	p, err := m.span(off, n)
+	if err != nil {
+		return 0, err
+	}
+	defer m.mu.RUnlock()
+
+	copy(p, buf[:n])
+	m.extend(int(off) + n)
+	return n, nil
+}
+
+// PutVarintAt encodes v as varint at off, like PutUvarintAt.
+func (m *Mmap) PutVarintAt(off int64, v int64) (n int, err error) {
+	// the zig-zag encoding of binary.PutVarint
+	ux := uint64(v) << 1
+	if v < 0 {
+		ux = ^ux
+	}
+	return m.PutUvarintAt(off, ux)
+}
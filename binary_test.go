@@ -0,0 +1,166 @@
+package mmap
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/ImSingee/tt"
+)
+
+func TestBinary(t *testing.T) {
+	t.Run("fixed", func(t *testing.T) {
+		mmap, err := New(NewReadWrite(""))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+			tt.AssertIsNotError(t, mmap.PutUint16At(1, 0xBEEF, order))
+			tt.AssertIsNotError(t, mmap.PutUint32At(3, 0xDEADBEEF, order))
+			tt.AssertIsNotError(t, mmap.PutUint64At(7, 0x0123456789ABCDEF, order))
+			tt.AssertIsNotError(t, mmap.PutFloat32At(15, 1.5, order))
+			tt.AssertIsNotError(t, mmap.PutFloat64At(19, math.Pi, order))
+			tt.AssertEqual(t, 27, mmap.Len())
+
+			u16, err := mmap.Uint16At(1, order)
+			tt.AssertIsNotError(t, err)
+			tt.AssertEqual(t, uint16(0xBEEF), u16)
+			tt.AssertEqual(t, uint16(0xBEEF), order.Uint16(mmap.data[1:]))
+
+			u32, err := mmap.Uint32At(3, order)
+			tt.AssertIsNotError(t, err)
+			tt.AssertEqual(t, uint32(0xDEADBEEF), u32)
+
+			u64, err := mmap.Uint64At(7, order)
+			tt.AssertIsNotError(t, err)
+			tt.AssertEqual(t, uint64(0x0123456789ABCDEF), u64)
+			tt.AssertEqual(t, uint64(0x0123456789ABCDEF), order.Uint64(mmap.data[7:]))
+
+			f32, err := mmap.Float32At(15, order)
+			tt.AssertIsNotError(t, err)
+			tt.AssertEqual(t, float32(1.5), f32)
+
+			f64, err := mmap.Float64At(19, order)
+			tt.AssertIsNotError(t, err)
+			tt.AssertEqual(t, math.Pi, f64)
+		}
+	})
+
+	t.Run("varint", func(t *testing.T) {
+		mmap, err := New(NewReadWrite(""))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		off := int64(0)
+		values := []int64{0, 1, -1, 300, -300, math.MaxInt64, math.MinInt64}
+		for _, v := range values {
+			n, err := mmap.PutVarintAt(off, v)
+			tt.AssertIsNotError(t, err)
+			off += int64(n)
+		}
+		n, err := mmap.PutUvarintAt(off, math.MaxUint64)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, binary.MaxVarintLen64, n)
+		tt.AssertEqual(t, int(off)+n, mmap.Len())
+
+		off = 0
+		for _, expect := range values {
+			v, n, err := mmap.VarintAt(off)
+			tt.AssertIsNotError(t, err)
+			tt.AssertEqual(t, expect, v)
+			off += int64(n)
+		}
+		u, _, err := mmap.UvarintAt(off)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, uint64(math.MaxUint64), u)
+
+		// truncated by Len
+		_, err = mmap.PutUvarintAt(int64(mmap.Len()), 1<<20)
+		tt.AssertIsNotError(t, err)
+		tt.AssertIsNotError(t, mmap.Truncate(mmap.Len()-1))
+		_, _, err = mmap.UvarintAt(int64(mmap.Len() - 2))
+		tt.AssertEqual(t, ErrOverflow, err)
+	})
+
+	t.Run("overflow", func(t *testing.T) {
+		f, err := newHelloWorldFile()
+		tt.AssertIsNotError(t, err)
+
+		mmap, err := New(NewReadWrite(f))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		_, err = mmap.Uint64At(int64(LenOfHelloWorld-7), binary.LittleEndian)
+		tt.AssertEqual(t, ErrOverflow, err)
+		_, err = mmap.Uint16At(-1, binary.LittleEndian)
+		tt.AssertEqual(t, ErrOverflow, err)
+		_, _, err = mmap.UvarintAt(int64(LenOfHelloWorld))
+		tt.AssertEqual(t, ErrOverflow, err)
+		tt.AssertEqual(t, ErrOverflow, mmap.PutUint32At(-1, 0, binary.LittleEndian))
+
+		v, err := mmap.Uint64At(int64(LenOfHelloWorld-8), binary.LittleEndian)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, binary.LittleEndian.Uint64([]byte(HelloWorld[LenOfHelloWorld-8:])), v)
+	})
+
+	t.Run("grow", func(t *testing.T) {
+		f, err := newHelloWorldFile()
+		tt.AssertIsNotError(t, err)
+
+		mmap, err := New(NewReadWrite(f))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		tt.AssertIsNotError(t, mmap.PutUint64At(oneMB, 42, binary.BigEndian))
+		tt.AssertTrue(t, mmap.Cap() >= oneMB+8)
+		tt.AssertEqual(t, oneMB+8, mmap.Len())
+
+		v, err := mmap.Uint64At(oneMB, binary.BigEndian)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, uint64(42), v)
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		mmap, err := New(NewReadWrite(""))
+		tt.AssertIsNotError(t, err)
+		closeMmap(t, mmap)
+
+		_, err = mmap.Uint32At(0, binary.LittleEndian)
+		tt.AssertEqual(t, ErrIsClosed, err)
+		tt.AssertEqual(t, ErrIsClosed, mmap.PutUint32At(0, 1, binary.LittleEndian))
+		_, err = mmap.PutVarintAt(0, 1)
+		tt.AssertEqual(t, ErrIsClosed, err)
+	})
+
+	t.Run("no-allocs", func(t *testing.T) {
+		mmap, err := New(NewReadWrite(""))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		allocs := testing.AllocsPerRun(100, func() {
+			_ = mmap.PutUint64At(8, 1, binary.LittleEndian)
+			_, _ = mmap.Uint64At(8, binary.LittleEndian)
+			_ = mmap.PutFloat64At(16, 1, binary.BigEndian)
+			_, _ = mmap.Float64At(16, binary.BigEndian)
+			_, _ = mmap.PutVarintAt(24, -1000)
+			_, _, _ = mmap.VarintAt(24)
+		})
+		tt.AssertEqual(t, float64(0), allocs)
+	})
+
+	t.Run("read-only", func(t *testing.T) {
+		f, err := newHelloWorldFile()
+		tt.AssertIsNotError(t, err)
+
+		mmap, err := New(NewReadOnly(f))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		tt.AssertEqual(t, ErrReadOnly, mmap.PutUint32At(0, 1, binary.LittleEndian))
+		_, err = mmap.PutUvarintAt(0, 1)
+		tt.AssertEqual(t, ErrReadOnly, err)
+
+		_, err = mmap.Uint32At(0, binary.LittleEndian)
+		tt.AssertIsNotError(t, err)
+	})
+}
@@ -0,0 +1,150 @@
+package mmap
+
+import (
+	"encoding/binary"
+	"sync"
+	"testing"
+
+	"github.com/ImSingee/tt"
+)
+
+func TestConcurrentGrow(t *testing.T) {
+	mmap, err := New(NewReadWrite(""))
+	tt.AssertIsNotError(t, err)
+	defer closeMmap(t, mmap)
+
+	const writers = 8
+	const readers = 4
+	const perWriter = 256
+	const stride = 64 * 1024 // every write lands past the previous Cap()
+
+	errs := make(chan error, writers+readers)
+	done := make(chan struct{})
+
+	var rwg sync.WaitGroup
+	for r := 0; r < readers; r++ {
+		rwg.Add(1)
+		go func() {
+			defer rwg.Done()
+
+			p := make([]byte, 8)
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				if length := mmap.Len(); length >= len(p) {
+					if _, err := mmap.ReadAt(p, int64(length-len(p))); err != nil {
+						errs <- err
+						return
+					}
+					if _, err := mmap.Bytes(0, len(p)); err != nil {
+						errs <- err
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	var wwg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wwg.Add(1)
+		go func(w int) {
+			defer wwg.Done()
+
+			p := make([]byte, 8)
+			for i := 0; i < perWriter; i++ {
+				off := int64((i*writers + w) * stride)
+				binary.LittleEndian.PutUint64(p, uint64(off))
+				if _, err := mmap.WriteAt(p, off); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(w)
+	}
+
+	wwg.Wait()
+	close(done)
+	rwg.Wait()
+	close(errs)
+
+	for err := range errs {
+		tt.AssertIsNotError(t, err)
+	}
+
+	tt.AssertTrue(t, mmap.Cap() >= writers*perWriter*stride)
+	tt.AssertEqual(t, (writers*perWriter-1)*stride+8, mmap.Len())
+
+	p := make([]byte, 8)
+	for i := 0; i < writers*perWriter; i++ {
+		off := int64(i * stride)
+		_, err := mmap.ReadAt(p, off)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, uint64(off), binary.LittleEndian.Uint64(p))
+	}
+}
+
+func TestConcurrentReaderDuringGrow(t *testing.T) {
+	mmap, err := New(NewReadWrite(""))
+	tt.AssertIsNotError(t, err)
+	defer closeMmap(t, mmap)
+
+	_, err = mmap.WriteAt([]byte(HelloWorld), 0)
+	tt.AssertIsNotError(t, err)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 1)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		p := make([]byte, LenOfHelloWorld)
+		for i := 0; i < 1000; i++ {
+			n, err := mmap.ReaderAt(0).Read(p)
+			if err != nil || n != LenOfHelloWorld || string(p) != HelloWorld {
+				errs <- err
+				return
+			}
+		}
+	}()
+
+	for i := 1; i <= 32; i++ {
+		tt.AssertIsNotError(t, mmap.EnsureCapacity(i*oneMB+1))
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		tt.AssertIsNotError(t, err)
+		t.Fatal("reader observed a torn mapping")
+	}
+}
+
+func TestConcurrentClose(t *testing.T) {
+	mmap, err := New(NewReadWrite(""))
+	tt.AssertIsNotError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 1000; j++ {
+				_, err := mmap.WriteAt([]byte{byte(j)}, int64(i*oneMB+j))
+				if err == ErrIsClosed {
+					return
+				}
+			}
+		}(i)
+	}
+
+	tt.AssertIsNotError(t, mmap.Close())
+	wg.Wait()
+	tt.AssertTrue(t, mmap.IsClosed())
+}
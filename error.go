@@ -5,3 +5,45 @@ import "fmt"
 var ErrIsClosed = fmt.Errorf("mmap is closed")
 
 var ErrOverflow = fmt.Errorf("mmap access out of bound")
+
+var ErrReadOnly = fmt.Errorf("mmap is read-only")
+
+var ErrReservationExceeded = fmt.Errorf("mmap reservation exceeded")
+
+var ErrUnaligned = fmt.Errorf("mmap access is not naturally aligned")
+
+// ErrOwnerDead is returned with a lock whose previous owner died while
+// holding it, the data it protects may be inconsistent.
+var ErrOwnerDead = fmt.Errorf("mmap lock owner died")
+
+var ErrNotLocked = fmt.Errorf("mmap lock is not held")
+
+var ErrFull = fmt.Errorf("mmap buffer is full")
+
+var ErrEmpty = fmt.Errorf("mmap buffer is empty")
+
+// ErrLapped is returned to a consumer that fell behind so far that the
+// records it was about to read have been overwritten.
+var ErrLapped = fmt.Errorf("mmap log consumer was lapped")
+
+// ErrBadHeader is returned when a structure laid over the map finds a header
+// that does not match what it was asked for.
+var ErrBadHeader = fmt.Errorf("mmap header does not match")
+
+var ErrNotSupported = fmt.Errorf("not supported on this platform")
+
+// MemlockLimitError is returned when pinning memory fails because it would
+// exceed RLIMIT_MEMLOCK, so callers can fall back to an unlocked mapping.
+type MemlockLimitError struct {
+	Size  int    // bytes that were to be locked
+	Limit uint64 // the RLIMIT_MEMLOCK soft limit in bytes
+	Err   error
+}
+
+func (e *MemlockLimitError) Error() string {
+	return fmt.Sprintf("mmap cannot lock %d bytes within RLIMIT_MEMLOCK of %d bytes: %v", e.Size, e.Limit, e.Err)
+}
+
+func (e *MemlockLimitError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,8 @@
+package mmap
+
+import "golang.org/x/sys/unix"
+
+// darwin has no fdatasync, fsync is the closest equivalent
+func fdatasync(fd int) error {
+	return unix.Fsync(fd)
+}
@@ -0,0 +1,7 @@
+package mmap
+
+import "golang.org/x/sys/unix"
+
+func fdatasync(fd int) error {
+	return unix.Fdatasync(fd)
+}
@@ -0,0 +1,86 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package mmap
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// SyncFlag selects how dirty pages are written back, see msync(2).
+type SyncFlag int
+
+const (
+	SyncWait       SyncFlag = unix.MS_SYNC
+	SyncAsync      SyncFlag = unix.MS_ASYNC
+	SyncInvalidate SyncFlag = unix.MS_INVALIDATE
+)
+
+// Flush writes all dirty pages back to the file and waits for completion.
+func (m *Mmap) Flush() error {
+	return m.flushAll(SyncWait)
+}
+
+// FlushAsync schedules all dirty pages to be written back without waiting.
+func (m *Mmap) FlushAsync() error {
+	return m.flushAll(SyncAsync)
+}
+
+func (m *Mmap) FlushRange(off int64, length int) error {
+	return m.FlushRangeWith(off, length, SyncWait)
+}
+
+func (m *Mmap) FlushRangeAsync(off int64, length int) error {
+	return m.FlushRangeWith(off, length, SyncAsync)
+}
+
+// FlushRangeWith calls msync for [off, off+length), extending the range down
+// to the page boundary. With SyncWait the file is also fdatasync-ed if the
+// Opener asks for it (see Args.SyncMetadata).
+func (m *Mmap) FlushRangeWith(off int64, length int, flags SyncFlag) error {
+	if err := m.rlock(0); err != nil {
+		return err
+	}
+	defer m.mu.RUnlock()
+
+	if off < 0 || length < 0 || off+int64(length) > int64(len(m.data)) {
+		return ErrOverflow
+	}
+
+	return m.flush(int(off), int(off)+length, flags)
+}
+
+func (m *Mmap) flushAll(flags SyncFlag) error {
+	if err := m.rlock(0); err != nil {
+		return err
+	}
+	defer m.mu.RUnlock()
+
+	return m.flush(0, len(m.data), flags)
+}
+
+func (m *Mmap) flush(start, end int, flags SyncFlag) error {
+	if start == end {
+		return nil
+	}
+
+	if err := unix.Msync(m.pages(start, end), int(flags)); err != nil {
+		return err
+	}
+
+	if flags&SyncWait != 0 {
+		if args, ok := m.args.(shouldSyncMetadata); ok && args.ShouldSyncMetadata() {
+			return m.datasync()
+		}
+	}
+
+	return nil
+}
+
+func (m *Mmap) datasync() error {
+	if m.file == nil {
+		return nil
+	}
+
+	return fdatasync(int(m.file.Fd()))
+}
@@ -0,0 +1,101 @@
+package mmap
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/ImSingee/tt"
+)
+
+func TestFlush(t *testing.T) {
+	t.Run("simple", func(t *testing.T) {
+		f, err := newHelloWorldFile()
+		tt.AssertIsNotError(t, err)
+
+		mmap, err := New(NewReadWrite(f))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		_, err = mmap.WriteAt([]byte("Bryan"), 6)
+		tt.AssertIsNotError(t, err)
+
+		tt.AssertIsNotError(t, mmap.Flush())
+		tt.AssertIsNotError(t, mmap.FlushAsync())
+
+		p, err := ioutil.ReadFile(f)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, "Hello Bryan!", string(p))
+	})
+
+	t.Run("range", func(t *testing.T) {
+		mmap, err := New(NewReadWrite(""))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		_, err = mmap.WriteAt([]byte(HelloWorld), 3*int64(pageSize)+5)
+		tt.AssertIsNotError(t, err)
+
+		// unaligned offsets are extended to the page boundary
+		tt.AssertIsNotError(t, mmap.FlushRange(3*int64(pageSize)+5, LenOfHelloWorld))
+		tt.AssertIsNotError(t, mmap.FlushRangeAsync(7, 1))
+		tt.AssertIsNotError(t, mmap.FlushRangeWith(1, 2, SyncAsync|SyncInvalidate))
+		tt.AssertIsNotError(t, mmap.FlushRange(0, 0))
+	})
+
+	t.Run("sync-metadata", func(t *testing.T) {
+		args := NewReadWrite("")
+		args.SyncMetadata = true
+
+		mmap, err := New(args)
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		_, err = mmap.WriteAt([]byte(HelloWorld), 0)
+		tt.AssertIsNotError(t, err)
+		tt.AssertIsNotError(t, mmap.Flush())
+	})
+
+	t.Run("overflow", func(t *testing.T) {
+		f, err := newHelloWorldFile()
+		tt.AssertIsNotError(t, err)
+
+		mmap, err := New(NewReadWrite(f))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		tt.AssertEqual(t, ErrOverflow, mmap.FlushRange(0, LenOfHelloWorld+1))
+		tt.AssertEqual(t, ErrOverflow, mmap.FlushRange(-1, 1))
+		tt.AssertEqual(t, ErrOverflow, mmap.FlushRange(1, -1))
+	})
+
+	t.Run("flush-after-close", func(t *testing.T) {
+		f, err := newHelloWorldFile()
+		tt.AssertIsNotError(t, err)
+
+		mmap, err := New(NewReadWrite(f))
+		tt.AssertIsNotError(t, err)
+		closeMmap(t, mmap)
+
+		tt.AssertEqual(t, ErrIsClosed, mmap.Flush())
+		tt.AssertEqual(t, ErrIsClosed, mmap.FlushRange(0, 1))
+	})
+}
+
+func TestGrowKeepsFlushedData(t *testing.T) {
+	f, err := newHelloWorldFile()
+	tt.AssertIsNotError(t, err)
+
+	mmap, err := New(NewReadWrite(f))
+	tt.AssertIsNotError(t, err)
+	defer closeMmap(t, mmap)
+
+	_, err = mmap.WriteAt([]byte("Bryan"), 6)
+	tt.AssertIsNotError(t, err)
+
+	tt.AssertIsNotError(t, mmap.EnsureCapacity(2*oneMB))
+
+	p := make([]byte, LenOfHelloWorld)
+	_, err = mmap.ReadAt(p, 0)
+	tt.AssertIsNotError(t, err)
+	tt.AssertEqual(t, "Hello Bryan!", string(p))
+}
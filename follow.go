@@ -0,0 +1,162 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package mmap
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// followPoll is how often a FollowReader looks for growth where it cannot
+// watch the file
+const followPoll = 100 * time.Millisecond
+
+// Refresh picks up growth of the file by others. With a shared header it
+// remaps to the capacity in the header, which every access does anyway, but
+// Cap only learns about it here. Otherwise it stats the file and, if it grew
+// beyond the capacity, remaps it and counts the new bytes as written, like New
+// does. The window grows with the file.
+func (m *Mmap) Refresh() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrIsClosed
+	}
+
+	if m.header {
+		return m.refreshShared()
+	}
+	return m.refreshFile()
+}
+
+func (m *Mmap) refreshFile() error {
+	if m.file == nil {
+		return nil
+	}
+
+	stat, err := m.file.Stat()
+	if err != nil {
+		return err
+	}
+
+	size := stat.Size() - m.offset()
+	if m.reserved != nil && size > int64(m.reservation()) {
+		size = int64(m.reservation())
+	}
+	if size <= int64(len(m.data)) {
+		return nil
+	}
+
+	if err := m.remap(int(size)); err != nil {
+		return err
+	}
+
+	m.extend(int(size))
+	return nil
+}
+
+// FollowReader reads the map sequentially like Reader, but at the end it waits
+// for the file to grow instead of returning io.EOF, like tail -f. It watches
+// the file with inotify where available and polls otherwise.
+type FollowReader struct {
+	m   *Mmap
+	ctx context.Context
+	pos int64
+	w   *watcher // nil while polling
+}
+
+// FollowReader returns a FollowReader at off. Read gives up with the error of
+// ctx once ctx is done. The reader must be closed.
+func (m *Mmap) FollowReader(ctx context.Context, off int64) (*FollowReader, error) {
+	if off < 0 {
+		return nil, ErrOverflow
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return nil, ErrIsClosed
+	}
+
+	r := &FollowReader{m: m, ctx: ctx, pos: off}
+
+	// without a watch, e.g. out of inotify instances, it polls
+	if m.file != nil {
+		if w, err := watchFile(m.file); err == nil {
+			r.w = w
+		}
+	}
+
+	return r, nil
+}
+
+func (r *FollowReader) Read(p []byte) (n int, err error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	for {
+		n, err = r.m.ReadAt(p, r.pos)
+		r.pos += int64(n)
+		if n > 0 {
+			return n, nil
+		}
+		if err != io.EOF {
+			return 0, err
+		}
+
+		if err := r.m.Refresh(); err != nil {
+			return 0, err
+		}
+		if int64(r.m.Len()) > r.pos {
+			continue
+		}
+
+		if err := r.wait(); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// wait blocks until the file may have grown or ctx is done
+func (r *FollowReader) wait() error {
+	return waitChange(r.ctx, r.w)
+}
+
+// waitChange blocks until w reports a change, or for followPoll without a
+// watcher, or until ctx is done
+func waitChange(ctx context.Context, w *watcher) error {
+	if w != nil {
+		return w.wait(ctx)
+	}
+
+	t := time.NewTimer(followPoll)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// Pos returns the current position, relative to the start of the map
+func (r *FollowReader) Pos() int64 {
+	return r.pos
+}
+
+// Close stops watching the file, it does not close the map.
+func (r *FollowReader) Close() error {
+	if r.w == nil {
+		return nil
+	}
+
+	w := r.w
+	r.w = nil
+	return w.close()
+}
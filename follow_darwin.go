@@ -0,0 +1,25 @@
+package mmap
+
+import (
+	"context"
+	"os"
+)
+
+// darwin has no inotify, FollowReader and ReloadingMmap poll instead
+type watcher struct{}
+
+func watchFile(f *os.File) (*watcher, error) {
+	return nil, ErrNotSupported
+}
+
+func watchDir(dir string) (*watcher, error) {
+	return nil, ErrNotSupported
+}
+
+func (w *watcher) wait(ctx context.Context) error {
+	return ErrNotSupported
+}
+
+func (w *watcher) close() error {
+	return nil
+}
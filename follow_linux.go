@@ -0,0 +1,83 @@
+package mmap
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// watcher waits for changes of a file or directory with inotify
+type watcher struct {
+	fd int
+}
+
+// watchFile watches for modifications of an open file, appending and
+// ftruncate both report IN_MODIFY. The watch is on the file rather than its
+// path, so it survives renames.
+func watchFile(f *os.File) (*watcher, error) {
+	return newWatcher(fmt.Sprintf("/proc/self/fd/%d", f.Fd()), unix.IN_MODIFY)
+}
+
+// watchDir watches for files in dir that are renamed into it or closed after
+// writing, which is how new versions of a file show up
+func watchDir(dir string) (*watcher, error) {
+	return newWatcher(dir, unix.IN_MOVED_TO|unix.IN_CLOSE_WRITE)
+}
+
+func newWatcher(name string, mask uint32) (*watcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := unix.InotifyAddWatch(fd, name, mask); err != nil {
+		_ = unix.Close(fd)
+		return nil, err
+	}
+
+	return &watcher{fd: fd}, nil
+}
+
+// wait blocks until something happened since the last wait or ctx is done,
+// it notices the latter every waitSlice.
+func (w *watcher) wait(ctx context.Context) error {
+	fds := []unix.PollFd{{Fd: int32(w.fd), Events: unix.POLLIN}}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		n, err := unix.Poll(fds, int(waitSlice.Milliseconds()))
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			return w.drain()
+		}
+	}
+}
+
+// drain reads all queued events, which only matter for waking up
+func (w *watcher) drain() error {
+	var buf [4096]byte
+	for {
+		_, err := unix.Read(w.fd, buf[:])
+		switch err {
+		case nil, unix.EINTR:
+		case unix.EAGAIN:
+			return nil
+		default:
+			return err
+		}
+	}
+}
+
+func (w *watcher) close() error {
+	return unix.Close(w.fd)
+}
@@ -0,0 +1,187 @@
+package mmap
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ImSingee/tt"
+)
+
+func appendString(file, s string) error {
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.WriteString(s)
+	return err
+}
+
+func TestRefresh(t *testing.T) {
+	t.Run("grown", func(t *testing.T) {
+		f, err := newHelloWorldFile()
+		tt.AssertIsNotError(t, err)
+
+		mmap, err := New(NewReadOnly(f))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		tt.AssertIsNotError(t, appendString(f, HelloWorld))
+
+		p := make([]byte, LenOfHelloWorld)
+		_, err = mmap.ReadAt(p, int64(LenOfHelloWorld))
+		tt.AssertEqual(t, io.EOF, err)
+
+		tt.AssertIsNotError(t, mmap.Refresh())
+		tt.AssertEqual(t, 2*LenOfHelloWorld, mmap.Len())
+		tt.AssertEqual(t, 2*LenOfHelloWorld, mmap.Cap())
+
+		_, err = mmap.ReadAt(p, int64(LenOfHelloWorld))
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, HelloWorld, string(p))
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		f, err := ioutil.TempFile("", "")
+		tt.AssertIsNotError(t, err)
+		_ = f.Close()
+
+		mmap, err := New(NewReadOnly(f.Name()))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		tt.AssertEqual(t, 0, mmap.Cap())
+		tt.AssertIsNotError(t, mmap.Refresh())
+		tt.AssertEqual(t, 0, mmap.Len())
+
+		tt.AssertIsNotError(t, appendString(f.Name(), HelloWorld))
+		tt.AssertIsNotError(t, mmap.Refresh())
+
+		p, err := mmap.Bytes(0, LenOfHelloWorld)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, HelloWorld, string(p))
+	})
+
+	t.Run("capacity-left", func(t *testing.T) {
+		mmap, err := New(NewReadWrite(""))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		// zeroed capacity behind Len does not count as written
+		_, err = mmap.WriteAt([]byte(HelloWorld), 0)
+		tt.AssertIsNotError(t, err)
+		tt.AssertIsNotError(t, mmap.Truncate(LenOfHelloWorld))
+		tt.AssertIsNotError(t, mmap.EnsureCapacity(oneMB))
+		tt.AssertIsNotError(t, mmap.Refresh())
+		tt.AssertEqual(t, LenOfHelloWorld, mmap.Len())
+		tt.AssertEqual(t, oneMB, mmap.Cap())
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		mmap, err := New(NewReadWrite(""))
+		tt.AssertIsNotError(t, err)
+		closeMmap(t, mmap)
+
+		tt.AssertEqual(t, ErrIsClosed, mmap.Refresh())
+	})
+}
+
+func TestFollowReader(t *testing.T) {
+	follow := func(t *testing.T, poll bool) {
+		f, err := newHelloWorldFile()
+		tt.AssertIsNotError(t, err)
+
+		mmap, err := New(NewReadOnly(f))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		r, err := mmap.FollowReader(context.Background(), 6)
+		tt.AssertIsNotError(t, err)
+		defer r.Close()
+		if poll {
+			tt.AssertIsNotError(t, r.Close())
+		}
+
+		p := make([]byte, 64)
+		n, err := r.Read(p)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, "world!", string(p[:n]))
+
+		appended := make(chan error, 1)
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			appended <- appendString(f, HelloWorld)
+		}()
+
+		n, err = r.Read(p)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, HelloWorld, string(p[:n]))
+		tt.AssertEqual(t, int64(2*LenOfHelloWorld), r.Pos())
+		tt.AssertIsNotError(t, <-appended)
+	}
+
+	t.Run("watch", func(t *testing.T) { follow(t, false) })
+	t.Run("poll", func(t *testing.T) { follow(t, true) })
+
+	t.Run("renamed", func(t *testing.T) {
+		f, err := newHelloWorldFile()
+		tt.AssertIsNotError(t, err)
+
+		mmap, err := New(NewReadOnly(f))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		r, err := mmap.FollowReader(context.Background(), int64(LenOfHelloWorld))
+		tt.AssertIsNotError(t, err)
+		defer r.Close()
+
+		renamed := f + ".renamed"
+		tt.AssertIsNotError(t, os.Rename(f, renamed))
+
+		appended := make(chan error, 1)
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			appended <- appendString(renamed, HelloWorld)
+		}()
+
+		p := make([]byte, 64)
+		n, err := r.Read(p)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, HelloWorld, string(p[:n]))
+		tt.AssertIsNotError(t, <-appended)
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		f, err := newHelloWorldFile()
+		tt.AssertIsNotError(t, err)
+
+		mmap, err := New(NewReadOnly(f))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		r, err := mmap.FollowReader(ctx, int64(LenOfHelloWorld))
+		tt.AssertIsNotError(t, err)
+		defer r.Close()
+
+		n, err := r.Read(make([]byte, 8))
+		tt.AssertEqual(t, context.DeadlineExceeded, err)
+		tt.AssertEqual(t, 0, n)
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		mmap, err := New(NewReadWrite(""))
+		tt.AssertIsNotError(t, err)
+		closeMmap(t, mmap)
+
+		_, err = mmap.FollowReader(context.Background(), 0)
+		tt.AssertEqual(t, ErrIsClosed, err)
+	})
+}
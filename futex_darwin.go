@@ -0,0 +1,25 @@
+package mmap
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// darwin has no public futex, waiters poll the word instead
+const futexPoll = time.Millisecond
+
+func futexWait(addr *uint32, val uint32, timeout time.Duration) error {
+	if atomic.LoadUint32(addr) != val {
+		return nil
+	}
+
+	if timeout > futexPoll {
+		timeout = futexPoll
+	}
+	time.Sleep(timeout)
+	return nil
+}
+
+func futexWake(addr *uint32, n int) (int, error) {
+	return 0, nil
+}
@@ -0,0 +1,37 @@
+package mmap
+
+import (
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// futexes are used without FUTEX_PRIVATE_FLAG, so the kernel keys them by the
+// file page rather than the address and waking works across processes
+const (
+	_FUTEX_WAIT = 0x0
+	_FUTEX_WAKE = 0x1
+)
+
+// futexWait sleeps while *addr holds val, until woken or timeout passes.
+// Spurious wakeups are possible, the caller has to check the word again.
+func futexWait(addr *uint32, val uint32, timeout time.Duration) error {
+	ts := unix.NsecToTimespec(int64(timeout))
+	_, _, errno := unix.Syscall6(unix.SYS_FUTEX, uintptr(unsafe.Pointer(addr)), _FUTEX_WAIT, uintptr(val), uintptr(unsafe.Pointer(&ts)), 0, 0)
+	switch errno {
+	case 0, unix.EAGAIN, unix.EINTR, unix.ETIMEDOUT:
+		return nil
+	default:
+		return errno
+	}
+}
+
+// futexWake wakes at most n waiters on addr and returns how many it woke
+func futexWake(addr *uint32, n int) (int, error) {
+	r, _, errno := unix.Syscall6(unix.SYS_FUTEX, uintptr(unsafe.Pointer(addr)), _FUTEX_WAKE, uintptr(n), 0, 0, 0)
+	if errno != 0 {
+		return 0, errno
+	}
+	return int(r), nil
+}
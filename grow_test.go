@@ -1,7 +1,7 @@
 package mmap
 
 import (
-	"errors"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
@@ -91,11 +91,70 @@ func TestDeleteFileBeforeGrow(t *testing.T) {
 	err = os.RemoveAll(filepath.Dir(f))
 	tt.AssertIsNotError(t, err)
 
+	// the file is held open, so growth still extends it
 	n, err := mmap.WriteAt([]byte{6}, 128) // will grow
-	tt.AssertIsError(t, err)
-	tt.AssertTrue(t, errors.Is(err, os.ErrNotExist))
-	tt.AssertEqual(t, 0, n)
-	tt.AssertTrue(t, mmap.IsClosed())
+	tt.AssertIsNotError(t, err)
+	tt.AssertEqual(t, 1, n)
+	tt.AssertEqual(t, 129, mmap.Len())
+
+	stat, err := mmap.File().Stat()
+	tt.AssertIsNotError(t, err)
+	tt.AssertTrue(t, stat.Size() >= 129)
+
+	_, err = os.Stat(f)
+	tt.AssertTrue(t, os.IsNotExist(err))
+}
+
+func TestRenameFileBeforeGrow(t *testing.T) {
+	f, err := newHelloWorldFile()
+	tt.AssertIsNotError(t, err)
+
+	args := NewReadWrite(f)
+	args.TrimOnClose = true
+
+	mmap, err := New(args)
+	tt.AssertIsNotError(t, err)
+
+	renamed := f + ".renamed"
+	tt.AssertIsNotError(t, os.Rename(f, renamed))
+
+	// a new file at the old path must not be what growth maps
+	tt.AssertIsNotError(t, ioutil.WriteFile(f, []byte("other"), 0644))
+
+	_, err = mmap.WriteAt([]byte(HelloWorld), 2*oneMB)
+	tt.AssertIsNotError(t, err)
+	tt.AssertEqual(t, HelloWorld, string(mmap.data[:LenOfHelloWorld]))
+	closeMmap(t, mmap)
+
+	p, err := ioutil.ReadFile(renamed)
+	tt.AssertIsNotError(t, err)
+	tt.AssertEqual(t, 2*oneMB+LenOfHelloWorld, len(p))
+	tt.AssertEqual(t, HelloWorld, string(p[:LenOfHelloWorld]))
+	tt.AssertEqual(t, HelloWorld, string(p[2*oneMB:]))
+
+	p, err = ioutil.ReadFile(f)
+	tt.AssertIsNotError(t, err)
+	tt.AssertEqual(t, "other", string(p))
+}
+
+func TestFile(t *testing.T) {
+	mmap, err := New(NewReadWrite(""))
+	tt.AssertIsNotError(t, err)
+
+	file := mmap.args.(*Args).File
+	tt.AssertEqual(t, file, mmap.File().Name())
+	tt.AssertEqual(t, int(mmap.File().Fd()), mmap.Fd())
+
+	closeMmap(t, mmap)
+	tt.AssertTrue(t, mmap.File() == nil)
+	tt.AssertEqual(t, -1, mmap.Fd())
+
+	anonymous, err := New(NewAnonymous(oneMB))
+	tt.AssertIsNotError(t, err)
+	defer closeMmap(t, anonymous)
+
+	tt.AssertTrue(t, anonymous.File() == nil)
+	tt.AssertEqual(t, -1, anonymous.Fd())
 }
 
 func TestGrowAfterClose(t *testing.T) {
@@ -110,3 +169,70 @@ func TestGrowAfterClose(t *testing.T) {
 	tt.AssertEqual(t, ErrIsClosed, err)
 	tt.AssertEqual(t, 0, n)
 }
+
+func TestGrowWithoutMremap(t *testing.T) {
+	defer func(old bool) { canMremap = old }(canMremap)
+	canMremap = false
+
+	mmap, err := New(NewReadWrite(""))
+	tt.AssertIsNotError(t, err)
+	defer closeMmap(t, mmap)
+
+	_, err = mmap.WriteAt([]byte(HelloWorld), 0)
+	tt.AssertIsNotError(t, err)
+
+	_, err = mmap.WriteAt([]byte(HelloWorld), 4*oneMB)
+	tt.AssertIsNotError(t, err)
+	tt.AssertEqual(t, 5*oneMB, mmap.Cap())
+
+	p, err := mmap.Bytes(0, LenOfHelloWorld)
+	tt.AssertIsNotError(t, err)
+	tt.AssertEqual(t, HelloWorld, string(p))
+}
+
+func BenchmarkGrow(b *testing.B) {
+	b.Run("mremap", func(b *testing.B) {
+		if !canMremap {
+			b.Skip("mremap is not available")
+		}
+		benchmarkGrow(b, true)
+	})
+
+	b.Run("remap", func(b *testing.B) {
+		benchmarkGrow(b, false)
+	})
+}
+
+// benchmarkGrow grows a fresh map to 4 GB with DefaultGrowPolicy, reading
+// every 64 KB after each step so re-faulting dropped pages is part of the cost
+func benchmarkGrow(b *testing.B, mremap bool) {
+	defer func(old bool) { canMremap = old }(canMremap)
+	canMremap = mremap
+
+	var sum byte
+	for i := 0; i < b.N; i++ {
+		mmap, err := New(NewReadWrite(""))
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		for mmap.Cap() < 4*oneGB {
+			if err := mmap.EnsureCapacity(mmap.Cap() + 1); err != nil {
+				b.Fatal(err)
+			}
+
+			for off := 0; off < mmap.Cap(); off += 64 * 1024 {
+				sum += mmap.data[off]
+			}
+		}
+
+		if err := mmap.Close(); err != nil {
+			b.Fatal(err)
+		}
+		_ = os.Remove(mmap.args.(*Args).File)
+	}
+
+	if sum != 0 {
+		b.Fatal("read non-zero bytes from a fresh map")
+	}
+}
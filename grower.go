@@ -1,5 +1,7 @@
 package mmap
 
+import "os"
+
 type Grower func(current int, atLeast int) (next int)
 
 const oneMB = 1024 * 1024
@@ -25,10 +27,19 @@ func alignOneMB(n int) int {
 	return align(n, oneMB)
 }
 
+var pageSize = os.Getpagesize()
+
+func alignPage(n int) int {
+	return align(n, pageSize)
+}
+
 func align(n, m int) int {
 	return ((n) + ((m) - 1)) & ^((m) - 1)
 }
 
 func (m *Mmap) ChangeGrowPolicy(newGrowPolicy Grower) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	m.grow = newGrowPolicy
 }
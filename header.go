@@ -0,0 +1,153 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package mmap
+
+import (
+	"sync/atomic"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+type shouldShareHeader interface {
+	ShouldShareHeader() bool
+}
+
+// sharedHeader is the layout of the page in front of the window, see
+// Args.SharedHeader
+type sharedHeader struct {
+	capacity   uint64 // the capacity every process maps
+	generation uint64 // bumped whenever capacity changes
+}
+
+// sharedHeaderSize is the space the header takes in the file, a whole page so
+// that the window stays page aligned
+var sharedHeaderSize = pageSize
+
+func (m *Mmap) headerSize() int {
+	if m.header {
+		return sharedHeaderSize
+	}
+	return 0
+}
+
+// sharedHeader returns the header, which lies right in front of data
+func (m *Mmap) sharedHeader() *sharedHeader {
+	return (*sharedHeader)(unsafe.Pointer(&m.raw()[m.slack-sharedHeaderSize]))
+}
+
+// stale reports whether another process changed the capacity in the shared
+// header since data was mapped. The caller must hold mu.
+func (m *Mmap) stale() bool {
+	return m.header && atomic.LoadUint64(&m.sharedHeader().generation) != m.gen
+}
+
+// lockFile takes an exclusive flock on the backing file and returns what
+// releases it. The lock belongs to the descriptor, so other maps of the file
+// in this process wait for it as well.
+func (m *Mmap) lockFile() (unlock func(), err error) {
+	fd := int(m.file.Fd())
+	if err := unix.Flock(fd, unix.LOCK_EX); err != nil {
+		return nil, err
+	}
+
+	return func() { _ = unix.Flock(fd, unix.LOCK_UN) }, nil
+}
+
+// openShared maps a file with a shared header under an exclusive flock. The
+// capacity is the one in the header, unless withCap is larger.
+func (m *Mmap) openShared(withCap int) (size int64, capacity int, err error) {
+	unlock, err := m.lockFile()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer unlock()
+
+	var header sharedHeader
+	if _, err := unix.Pread(int(m.file.Fd()), (*[unsafe.Sizeof(header)]byte)(unsafe.Pointer(&header))[:], m.args.Offset()); err != nil {
+		return 0, 0, err
+	}
+
+	capacity = int(header.capacity)
+	if withCap > capacity {
+		capacity = withCap
+	}
+
+	if size, err = m.open(capacity); err != nil {
+		return 0, 0, err
+	}
+
+	h := m.sharedHeader()
+	if uint64(capacity) != header.capacity {
+		atomic.StoreUint64(&h.capacity, uint64(capacity))
+		atomic.AddUint64(&h.generation, 1)
+	}
+	m.gen = atomic.LoadUint64(&h.generation)
+
+	return size, capacity, nil
+}
+
+// growShared grows a map with a shared header to hold size bytes, under an
+// exclusive flock so that processes grow one after the other. It starts from
+// the capacity in the header, which another process may have grown already,
+// and bumps the generation so that the others remap as well. exact grows to
+// size itself instead of what the Grower says, it cannot shrink.
+func (m *Mmap) growShared(size int, exact bool) error {
+	unlock, err := m.lockFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current := int(atomic.LoadUint64(&m.sharedHeader().capacity))
+	next := current
+	switch {
+	case exact && size < current:
+		return ErrNotSupported
+	case exact:
+		next = size
+	case size > current:
+		if next, err = m.next(current, size); err != nil {
+			return err
+		}
+	}
+
+	if next != len(m.data) {
+		if err := m.remap(next); err != nil {
+			return err
+		}
+	}
+
+	h := m.sharedHeader()
+	if next != current {
+		atomic.StoreUint64(&h.capacity, uint64(next))
+		atomic.AddUint64(&h.generation, 1)
+	}
+	m.gen = atomic.LoadUint64(&h.generation)
+
+	return nil
+}
+
+// refreshShared remaps to the capacity in the shared header if another process
+// changed it
+func (m *Mmap) refreshShared() error {
+	if !m.stale() {
+		return nil
+	}
+
+	h := m.sharedHeader()
+	gen := atomic.LoadUint64(&h.generation)
+	capacity := int(atomic.LoadUint64(&h.capacity))
+
+	if capacity != len(m.data) {
+		if err := m.remap(capacity); err != nil {
+			return err
+		}
+	}
+
+	// like in New, everything other processes added counts as written
+	m.extend(capacity)
+	m.gen = gen
+	return nil
+}
@@ -0,0 +1,188 @@
+package mmap
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/ImSingee/tt"
+)
+
+const growRounds = 6
+
+// growAlternately takes the turns of player in growing the map, every turn
+// writes a marker a megabyte further than the last one. The turn is kept in
+// the first word of the map.
+func growAlternately(file string, player int) error {
+	args := NewReadWrite(file)
+	args.InitLength = 4096
+	args.SharedHeader = true
+
+	mmap, err := New(args)
+	if err != nil {
+		return err
+	}
+	defer mmap.Close()
+
+	for turn := player; turn < growRounds; turn += 2 {
+		for {
+			v, err := mmap.AtomicLoadUint32(0)
+			if err != nil {
+				return err
+			}
+			if v == uint32(turn) {
+				break
+			}
+			if _, err := mmap.WaitUint32(0, v, -1); err != nil {
+				return err
+			}
+		}
+
+		if err := mmap.PutUint64At(int64(turn+1)*oneMB, uint64(turn+1), binary.LittleEndian); err != nil {
+			return err
+		}
+		if err := mmap.AtomicStoreUint32(0, uint32(turn+1)); err != nil {
+			return err
+		}
+		if _, err := mmap.NotifyUint32(0, wakeAll); err != nil {
+			return err
+		}
+	}
+
+	// wait for the last turn and check everyone's markers
+	if _, err := mmap.WaitUint32(0, growRounds-1, -1); err != nil {
+		return err
+	}
+	for turn := 0; turn < growRounds; turn++ {
+		v, err := mmap.Uint64At(int64(turn+1)*oneMB, binary.LittleEndian)
+		if err != nil {
+			return err
+		}
+		if v != uint64(turn+1) {
+			return fmt.Errorf("marker %d is %d", turn+1, v)
+		}
+	}
+
+	return nil
+}
+
+func newSharedHeaderMmap(t *testing.T, file string) *Mmap {
+	args := NewReadWrite(file)
+	args.SharedHeader = true
+
+	mmap, err := New(args)
+	tt.AssertIsNotError(t, err)
+	return mmap
+}
+
+func TestSharedHeader(t *testing.T) {
+	t.Run("processes", func(t *testing.T) {
+		f, err := ioutil.TempFile("", "")
+		tt.AssertIsNotError(t, err)
+		_ = f.Close()
+
+		cmd := runHelper("grow-alternately", f.Name())
+		tt.AssertIsNotError(t, cmd.Start())
+
+		tt.AssertIsNotError(t, growAlternately(f.Name(), 0))
+		tt.AssertIsNotError(t, cmd.Wait())
+	})
+
+	t.Run("refresh", func(t *testing.T) {
+		a := newSharedHeaderMmap(t, "")
+		defer closeMmap(t, a)
+
+		file := a.args.(*Args).File
+		b := newSharedHeaderMmap(t, file)
+		defer closeMmap(t, b)
+
+		tt.AssertEqual(t, DefaultInitLength, a.Cap())
+		tt.AssertEqual(t, DefaultInitLength, b.Cap())
+
+		_, err := a.WriteAt([]byte(HelloWorld), 3*oneMB)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, 4*oneMB, a.Cap())
+
+		// b only learns about it on access or Refresh
+		tt.AssertEqual(t, DefaultInitLength, b.Cap())
+		tt.AssertIsNotError(t, b.Refresh())
+		tt.AssertEqual(t, 4*oneMB, b.Cap())
+		tt.AssertEqual(t, 4*oneMB, b.Len())
+
+		p, err := b.Bytes(3*oneMB, LenOfHelloWorld)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, HelloWorld, string(p))
+
+		// growing b starts from the capacity a left behind
+		_, err = b.WriteAt([]byte(HelloWorld), 5*oneMB)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, 8*oneMB, b.Cap())
+
+		p = make([]byte, LenOfHelloWorld)
+		_, err = a.ReadAt(p, 5*oneMB)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, HelloWorld, string(p))
+		tt.AssertEqual(t, 8*oneMB, a.Cap())
+	})
+
+	t.Run("layout", func(t *testing.T) {
+		mmap := newSharedHeaderMmap(t, "")
+		file := mmap.args.(*Args).File
+
+		_, err := mmap.WriteAt([]byte(HelloWorld), 0)
+		tt.AssertIsNotError(t, err)
+
+		// the header cannot shrink and Close leaves the file alone
+		tt.AssertEqual(t, ErrNotSupported, mmap.Truncate(oneMB/2))
+		tt.AssertIsNotError(t, mmap.Truncate(2*oneMB))
+		closeMmap(t, mmap)
+
+		p, err := ioutil.ReadFile(file)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, sharedHeaderSize+2*oneMB, len(p))
+		tt.AssertEqual(t, uint64(2*oneMB), binary.LittleEndian.Uint64(p))
+		tt.AssertEqual(t, HelloWorld, string(p[sharedHeaderSize:sharedHeaderSize+LenOfHelloWorld]))
+
+		// the header rather than the file decides the capacity
+		args := NewReadWrite(file)
+		args.SharedHeader = true
+		args.InitLength = 4096
+
+		mmap, err = New(args)
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+		tt.AssertEqual(t, 2*oneMB, mmap.Cap())
+		tt.AssertEqual(t, HelloWorld, string(mmap.data[:LenOfHelloWorld]))
+	})
+
+	t.Run("window", func(t *testing.T) {
+		f, err := ioutil.TempFile("", "")
+		tt.AssertIsNotError(t, err)
+		_, err = f.WriteString(HelloWorld[:8])
+		tt.AssertIsNotError(t, err)
+		_ = f.Close()
+
+		args := NewReadWrite(f.Name())
+		args.FileOffset = 8
+		args.InitLength = 16
+		args.SharedHeader = true
+
+		mmap, err := New(args)
+		tt.AssertIsNotError(t, err)
+
+		_, err = mmap.WriteAt([]byte(HelloWorld), 0)
+		tt.AssertIsNotError(t, err)
+		closeMmap(t, mmap)
+
+		p, err := ioutil.ReadFile(f.Name())
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, "Hello wo", string(p[:8]))
+		tt.AssertEqual(t, uint64(16), binary.LittleEndian.Uint64(p[8:]))
+		tt.AssertEqual(t, HelloWorld, string(p[8+sharedHeaderSize:8+sharedHeaderSize+LenOfHelloWorld]))
+
+		args.FileOffset = 4
+		_, err = New(args)
+		tt.AssertEqual(t, ErrUnaligned, err)
+	})
+}
@@ -0,0 +1,398 @@
+package mmap
+
+import (
+	"math"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// The synchronization primitives keep all of their state in a 4 byte word of
+// the map, so they work across every process that maps the same file. A zero
+// word is unlocked, as in freshly grown space of the file.
+//
+// Waiters sleep on the word with a futex for waitSlice at a time and let go of
+// the map in between, so that growth and Close are delayed by one slice at
+// most and a mapping that moved is picked up.
+//
+// Mutex and the writer of RWMutex record the PID of their owner. A locker that
+// finds the owner gone takes the lock over and gets ErrOwnerDead along with
+// it. PIDs are reused eventually, and a killed process counts as alive until
+// its parent reaps it. Readers of RWMutex are not tracked and cannot be
+// recovered.
+//
+// All processes using a primitive must share one PID namespace. An owner in
+// another one, e.g. a container that maps the file through a bind mount, has
+// a PID that means nothing here or names someone else, so it is taken for
+// dead and two processes end up holding the lock.
+
+const waitSlice = 20 * time.Millisecond
+
+const (
+	wordWaiters = 1 << 31         // someone sleeps on the word
+	wordWriter  = 1 << 30         // RWMutex: a writer holds the lock
+	wordPending = 1 << 29         // RWMutex: a writer waits, new readers hold off
+	wordValue   = wordPending - 1 // the owner PID or a count
+)
+
+const wakeAll = math.MaxInt32
+
+var pid = uint32(os.Getpid())
+
+// alive reports whether the process with the given PID still exists in the
+// PID namespace of this process
+func alive(pid uint32) bool {
+	err := unix.Kill(int(pid), 0)
+	return err == nil || err == unix.EPERM
+}
+
+// syncWord checks that the word at off can hold a synchronization primitive
+// and makes it part of Len, so that it survives Close.
+func (m *Mmap) syncWord(off int64) error {
+	if _, err := m.writableWord(off, 4); err != nil {
+		return err
+	}
+	defer m.mu.RUnlock()
+
+	m.extend(int(off) + 4)
+	return nil
+}
+
+// onWord calls step with the word at off until it is done. While it is not,
+// onWord sleeps for a slice as long as the word holds the value step returned.
+func (m *Mmap) onWord(off int64, step func(w *uint32) (wait uint32, done bool, err error)) error {
+	for {
+		p, err := m.writableWord(off, 4)
+		if err != nil {
+			return err
+		}
+
+		w := (*uint32)(p)
+		wait, done, err := step(w)
+		if !done && err == nil {
+			err = futexWait(w, wait, waitSlice)
+		}
+		m.mu.RUnlock()
+
+		if done || err != nil {
+			return err
+		}
+	}
+}
+
+// once calls step with the word at off exactly once
+func (m *Mmap) once(off int64, step func(w *uint32) error) error {
+	return m.onWord(off, func(w *uint32) (uint32, bool, error) {
+		return 0, true, step(w)
+	})
+}
+
+// wakeIf wakes n waiters on w if old had the waiters bit
+func wakeIf(w *uint32, old uint32, n int) error {
+	if old&wordWaiters == 0 {
+		return nil
+	}
+
+	_, err := futexWake(w, n)
+	return err
+}
+
+// Mutex is a mutual exclusion lock shared by all processes mapping the file.
+type Mutex struct {
+	m   *Mmap
+	off int64
+}
+
+// Mutex returns the mutex whose word is at off, which must be 4 byte aligned.
+func (m *Mmap) Mutex(off int64) (*Mutex, error) {
+	if err := m.syncWord(off); err != nil {
+		return nil, err
+	}
+	return &Mutex{m, off}, nil
+}
+
+// Lock locks mu, waiting until it is available. It returns ErrOwnerDead if
+// it took mu over from a dead process, mu is locked all the same.
+func (mu *Mutex) Lock() error {
+	// once it slept, a locker can no longer tell whether others sleep as well
+	var contended uint32
+
+	return mu.m.onWord(mu.off, func(w *uint32) (uint32, bool, error) {
+		old := atomic.LoadUint32(w)
+		switch owner := old & wordValue; {
+		case owner == 0:
+			return old, atomic.CompareAndSwapUint32(w, old, pid|contended|old&wordWaiters), nil
+		case !alive(owner):
+			if atomic.CompareAndSwapUint32(w, old, pid|old&wordWaiters) {
+				return 0, true, ErrOwnerDead
+			}
+			return old, false, nil
+		case old&wordWaiters == 0:
+			atomic.CompareAndSwapUint32(w, old, old|wordWaiters)
+			return old, false, nil
+		default:
+			contended = wordWaiters
+			return old, false, nil
+		}
+	})
+}
+
+// TryLock locks mu if it is available and reports whether it did.
+func (mu *Mutex) TryLock() (locked bool, err error) {
+	err = mu.m.once(mu.off, func(w *uint32) error {
+		old := atomic.LoadUint32(w)
+		switch owner := old & wordValue; {
+		case owner == 0:
+			locked = atomic.CompareAndSwapUint32(w, old, pid|old&wordWaiters)
+		case !alive(owner):
+			if locked = atomic.CompareAndSwapUint32(w, old, pid|old&wordWaiters); locked {
+				return ErrOwnerDead
+			}
+		}
+		return nil
+	})
+	return
+}
+
+// Unlock unlocks mu, which must be locked by this process.
+func (mu *Mutex) Unlock() error {
+	return mu.m.onWord(mu.off, func(w *uint32) (uint32, bool, error) {
+		old := atomic.LoadUint32(w)
+		if old&wordValue != pid {
+			return 0, true, ErrNotLocked
+		}
+		if !atomic.CompareAndSwapUint32(w, old, 0) {
+			return old, false, nil
+		}
+		return 0, true, wakeIf(w, old, 1)
+	})
+}
+
+// RWMutex is a reader/writer lock shared by all processes mapping the file.
+// A waiting writer keeps new readers from locking.
+type RWMutex struct {
+	m   *Mmap
+	off int64
+}
+
+// RWMutex returns the lock whose word is at off, which must be 4 byte aligned.
+func (m *Mmap) RWMutex(off int64) (*RWMutex, error) {
+	if err := m.syncWord(off); err != nil {
+		return nil, err
+	}
+	return &RWMutex{m, off}, nil
+}
+
+// Lock locks rw for writing, like Mutex.Lock.
+func (rw *RWMutex) Lock() error {
+	return rw.m.onWord(rw.off, func(w *uint32) (uint32, bool, error) {
+		old := atomic.LoadUint32(w)
+		switch {
+		case old&wordWriter == 0 && old&wordValue == 0:
+			return old, atomic.CompareAndSwapUint32(w, old, pid|wordWriter|old&wordWaiters), nil
+		case old&wordWriter != 0 && !alive(old&wordValue):
+			if atomic.CompareAndSwapUint32(w, old, pid|wordWriter|old&wordWaiters) {
+				return 0, true, ErrOwnerDead
+			}
+			return old, false, nil
+		case old&(wordWaiters|wordPending) != wordWaiters|wordPending:
+			atomic.CompareAndSwapUint32(w, old, old|wordWaiters|wordPending)
+			return old, false, nil
+		default:
+			return old, false, nil
+		}
+	})
+}
+
+// Unlock unlocks rw for writing, it must be locked for writing by this
+// process.
+func (rw *RWMutex) Unlock() error {
+	return rw.m.onWord(rw.off, func(w *uint32) (uint32, bool, error) {
+		old := atomic.LoadUint32(w)
+		if old&wordWriter == 0 || old&wordValue != pid {
+			return 0, true, ErrNotLocked
+		}
+		if !atomic.CompareAndSwapUint32(w, old, 0) {
+			return old, false, nil
+		}
+		return 0, true, wakeIf(w, old, wakeAll)
+	})
+}
+
+// RLock locks rw for reading. It returns ErrOwnerDead if it took rw over from
+// a writer that died, rw is locked for reading all the same.
+func (rw *RWMutex) RLock() error {
+	return rw.m.onWord(rw.off, func(w *uint32) (uint32, bool, error) {
+		old := atomic.LoadUint32(w)
+		switch {
+		case old&wordWriter != 0 && !alive(old&wordValue):
+			if !atomic.CompareAndSwapUint32(w, old, 1) {
+				return old, false, nil
+			}
+			if err := wakeIf(w, old, wakeAll); err != nil {
+				return 0, true, err
+			}
+			return 0, true, ErrOwnerDead
+		case old&(wordWriter|wordPending) == 0:
+			if old&wordValue == wordValue {
+				return 0, true, ErrOverflow
+			}
+			return old, atomic.CompareAndSwapUint32(w, old, old+1), nil
+		case old&wordWaiters == 0:
+			atomic.CompareAndSwapUint32(w, old, old|wordWaiters)
+			return old, false, nil
+		default:
+			return old, false, nil
+		}
+	})
+}
+
+// RUnlock undoes a single RLock.
+func (rw *RWMutex) RUnlock() error {
+	return rw.m.onWord(rw.off, func(w *uint32) (uint32, bool, error) {
+		old := atomic.LoadUint32(w)
+		if old&wordWriter != 0 || old&wordValue == 0 {
+			return 0, true, ErrNotLocked
+		}
+
+		next := old - 1
+		if next&wordValue == 0 {
+			// the last reader lets everyone waiting compete again
+			next = 0
+		}
+		if !atomic.CompareAndSwapUint32(w, old, next) {
+			return old, false, nil
+		}
+		if next != 0 {
+			return 0, true, nil
+		}
+		return 0, true, wakeIf(w, old, wakeAll)
+	})
+}
+
+// Semaphore is a counting semaphore shared by all processes mapping the file.
+type Semaphore struct {
+	m   *Mmap
+	off int64
+}
+
+// Semaphore returns the semaphore whose word is at off, which must be 4 byte
+// aligned. A zero word has no permits.
+func (m *Mmap) Semaphore(off int64) (*Semaphore, error) {
+	if err := m.syncWord(off); err != nil {
+		return nil, err
+	}
+	return &Semaphore{m, off}, nil
+}
+
+// Init sets the number of permits, before any process uses s.
+func (s *Semaphore) Init(permits uint32) error {
+	if permits > wordValue {
+		return ErrOverflow
+	}
+
+	return s.m.once(s.off, func(w *uint32) error {
+		atomic.StoreUint32(w, permits)
+		return nil
+	})
+}
+
+// Acquire takes a permit, waiting until one is available.
+func (s *Semaphore) Acquire() error {
+	return s.m.onWord(s.off, func(w *uint32) (uint32, bool, error) {
+		old := atomic.LoadUint32(w)
+		switch {
+		case old&wordValue > 0:
+			return old, atomic.CompareAndSwapUint32(w, old, old-1), nil
+		case old&wordWaiters == 0:
+			atomic.CompareAndSwapUint32(w, old, old|wordWaiters)
+			return old, false, nil
+		default:
+			return old, false, nil
+		}
+	})
+}
+
+// TryAcquire takes a permit if one is available and reports whether it did.
+func (s *Semaphore) TryAcquire() (acquired bool, err error) {
+	err = s.m.once(s.off, func(w *uint32) error {
+		if old := atomic.LoadUint32(w); old&wordValue > 0 {
+			acquired = atomic.CompareAndSwapUint32(w, old, old-1)
+		}
+		return nil
+	})
+	return
+}
+
+// Release returns a permit.
+func (s *Semaphore) Release() error {
+	return s.m.onWord(s.off, func(w *uint32) (uint32, bool, error) {
+		old := atomic.LoadUint32(w)
+		if old&wordValue == wordValue {
+			return 0, true, ErrOverflow
+		}
+		if !atomic.CompareAndSwapUint32(w, old, (old+1)&^wordWaiters) {
+			return old, false, nil
+		}
+		return 0, true, wakeIf(w, old, wakeAll)
+	})
+}
+
+// Cond is a condition variable shared by all processes mapping the file, its
+// word is a sequence bumped by every Signal and Broadcast. As with pthread
+// condition variables, Wait may return spuriously.
+type Cond struct {
+	m   *Mmap
+	off int64
+}
+
+// Cond returns the condition variable whose word is at off, which must be 4
+// byte aligned.
+func (m *Mmap) Cond(off int64) (*Cond, error) {
+	if err := m.syncWord(off); err != nil {
+		return nil, err
+	}
+	return &Cond{m, off}, nil
+}
+
+// Wait unlocks mu, waits for Signal or Broadcast and locks mu again before it
+// returns. Locking mu again may return ErrOwnerDead.
+func (c *Cond) Wait(mu *Mutex) error {
+	seq, err := c.m.AtomicLoadUint32(c.off)
+	if err != nil {
+		return err
+	}
+
+	if err := mu.Unlock(); err != nil {
+		return err
+	}
+
+	err = c.m.onWord(c.off, func(w *uint32) (uint32, bool, error) {
+		return seq, atomic.LoadUint32(w) != seq, nil
+	})
+
+	if lerr := mu.Lock(); lerr != nil {
+		return lerr
+	}
+	return err
+}
+
+// Signal wakes one process waiting on c.
+func (c *Cond) Signal() error {
+	return c.notify(1)
+}
+
+// Broadcast wakes all processes waiting on c.
+func (c *Cond) Broadcast() error {
+	return c.notify(wakeAll)
+}
+
+func (c *Cond) notify(n int) error {
+	return c.m.once(c.off, func(w *uint32) error {
+		atomic.AddUint32(w, 1)
+		_, err := futexWake(w, n)
+		return err
+	})
+}
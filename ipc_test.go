@@ -0,0 +1,453 @@
+package mmap
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ImSingee/tt"
+)
+
+// the layout of the map shared with helper processes
+const (
+	ipcMutex   = 0
+	ipcCounter = 8
+	ipcRWMutex = 16
+	ipcCond    = 24
+	ipcFlag    = 32
+	ipcRing    = 64
+)
+
+const helperMessages = 10000
+
+const helperIncrements = 500
+
+// TestHelperProcess is no real test, the other tests run it in a child process
+// to work on their map from there.
+func TestHelperProcess(t *testing.T) {
+	op := os.Getenv("MMAP_HELPER")
+	if op == "" {
+		return
+	}
+
+	if err := helperProcess(op, os.Getenv("MMAP_HELPER_FILE")); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	os.Exit(0)
+}
+
+func helperProcess(op, file string) error {
+	if op == "grow-alternately" {
+		return growAlternately(file, 1)
+	}
+
+	mmap, err := New(NewReadWrite(file))
+	if err != nil {
+		return err
+	}
+
+	mu, err := mmap.Mutex(ipcMutex)
+	if err != nil {
+		return err
+	}
+
+	switch op {
+	case "increment":
+		for i := 0; i < helperIncrements; i++ {
+			if err := increment(mmap, mu); err != nil {
+				return err
+			}
+		}
+	case "lock-and-die":
+		if err := mu.Lock(); err != nil {
+			return err
+		}
+		os.Exit(0)
+	case "write-lock-and-die":
+		rw, err := mmap.RWMutex(ipcRWMutex)
+		if err != nil {
+			return err
+		}
+		if err := rw.Lock(); err != nil {
+			return err
+		}
+		os.Exit(0)
+	case "signal":
+		cond, err := mmap.Cond(ipcCond)
+		if err != nil {
+			return err
+		}
+		if err := mu.Lock(); err != nil {
+			return err
+		}
+		if err := mmap.AtomicStoreUint32(ipcFlag, 1); err != nil {
+			return err
+		}
+		if err := cond.Signal(); err != nil {
+			return err
+		}
+		return mu.Unlock()
+	case "notify":
+		if _, err := mmap.AtomicAddUint32(ipcFlag, 1); err != nil {
+			return err
+		}
+		if _, err := mmap.NotifyUint32(ipcFlag, wakeAll); err != nil {
+			return err
+		}
+	case "produce":
+		ring, err := mmap.RingBuffer(ipcRing, 0)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < helperMessages; i++ {
+			if _, err := ring.Write(ringMessage(i)); err != nil {
+				return err
+			}
+		}
+	case "publish":
+		log, err := mmap.SharedLog(ipcRing, 0)
+		if err != nil {
+			return err
+		}
+		producer, err := strconv.Atoi(os.Getenv("MMAP_HELPER_PRODUCER"))
+		if err != nil {
+			return err
+		}
+		for i := 0; i < helperMessages; i++ {
+			if _, err := log.Append(logRecord(producer, i)); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unknown helper %q", op)
+	}
+
+	return mmap.Close()
+}
+
+func runHelper(op, file string, env ...string) *exec.Cmd {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestHelperProcess$")
+	cmd.Env = append(os.Environ(), "MMAP_HELPER="+op, "MMAP_HELPER_FILE="+file)
+	cmd.Env = append(cmd.Env, env...)
+	cmd.Stderr = os.Stderr
+	return cmd
+}
+
+// increment adds one to the counter, deliberately without atomics
+func increment(mmap *Mmap, mu *Mutex) error {
+	if err := mu.Lock(); err != nil {
+		return err
+	}
+
+	v, err := mmap.Uint64At(ipcCounter, binary.LittleEndian)
+	if err != nil {
+		return err
+	}
+	if err := mmap.PutUint64At(ipcCounter, v+1, binary.LittleEndian); err != nil {
+		return err
+	}
+
+	return mu.Unlock()
+}
+
+func newIpcMmap(t *testing.T) (*Mmap, string) {
+	mmap, err := New(NewReadWrite(""))
+	tt.AssertIsNotError(t, err)
+
+	// the helpers see the whole layout within Len
+	_, err = mmap.WriteAt(make([]byte, 64), 0)
+	tt.AssertIsNotError(t, err)
+
+	return mmap, mmap.args.(*Args).File
+}
+
+func TestMutex(t *testing.T) {
+	t.Run("processes", func(t *testing.T) {
+		mmap, file := newIpcMmap(t)
+		defer closeMmap(t, mmap)
+
+		mu, err := mmap.Mutex(ipcMutex)
+		tt.AssertIsNotError(t, err)
+
+		const helpers = 4
+		var cmds []*exec.Cmd
+		for i := 0; i < helpers; i++ {
+			cmd := runHelper("increment", file)
+			tt.AssertIsNotError(t, cmd.Start())
+			cmds = append(cmds, cmd)
+		}
+
+		for i := 0; i < helperIncrements; i++ {
+			tt.AssertIsNotError(t, increment(mmap, mu))
+		}
+		for _, cmd := range cmds {
+			tt.AssertIsNotError(t, cmd.Wait())
+		}
+
+		v, err := mmap.Uint64At(ipcCounter, binary.LittleEndian)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, uint64((helpers+1)*helperIncrements), v)
+	})
+
+	t.Run("owner-dead", func(t *testing.T) {
+		mmap, file := newIpcMmap(t)
+		defer closeMmap(t, mmap)
+
+		mu, err := mmap.Mutex(ipcMutex)
+		tt.AssertIsNotError(t, err)
+
+		tt.AssertIsNotError(t, runHelper("lock-and-die", file).Run())
+
+		tt.AssertEqual(t, ErrOwnerDead, mu.Lock())
+		tt.AssertIsNotError(t, mu.Unlock())
+		tt.AssertIsNotError(t, mu.Lock())
+		tt.AssertIsNotError(t, mu.Unlock())
+	})
+
+	t.Run("try-lock", func(t *testing.T) {
+		mmap, _ := newIpcMmap(t)
+		defer closeMmap(t, mmap)
+
+		mu, err := mmap.Mutex(ipcMutex)
+		tt.AssertIsNotError(t, err)
+
+		tt.AssertEqual(t, ErrNotLocked, mu.Unlock())
+
+		locked, err := mu.TryLock()
+		tt.AssertIsNotError(t, err)
+		tt.AssertTrue(t, locked)
+
+		locked, err = mu.TryLock()
+		tt.AssertIsNotError(t, err)
+		tt.AssertFalse(t, locked)
+
+		tt.AssertIsNotError(t, mu.Unlock())
+	})
+
+	t.Run("goroutines", func(t *testing.T) {
+		mmap, file := newIpcMmap(t)
+		defer closeMmap(t, mmap)
+
+		// a second map of the same file shares the lock
+		other, err := New(NewReadWrite(file))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, other)
+
+		var wg sync.WaitGroup
+		for _, m := range []*Mmap{mmap, other, mmap, other} {
+			mu, err := m.Mutex(ipcMutex)
+			tt.AssertIsNotError(t, err)
+
+			wg.Add(1)
+			go func(m *Mmap, mu *Mutex) {
+				defer wg.Done()
+				for j := 0; j < 1000; j++ {
+					if err := increment(m, mu); err != nil {
+						t.Error(err)
+						return
+					}
+				}
+			}(m, mu)
+		}
+		wg.Wait()
+
+		v, err := mmap.Uint64At(ipcCounter, binary.LittleEndian)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, uint64(4000), v)
+	})
+
+	t.Run("growth-while-waiting", func(t *testing.T) {
+		mmap, _ := newIpcMmap(t)
+		defer closeMmap(t, mmap)
+
+		mu, err := mmap.Mutex(ipcMutex)
+		tt.AssertIsNotError(t, err)
+		tt.AssertIsNotError(t, mu.Lock())
+
+		locked := make(chan error, 1)
+		go func() {
+			locked <- mu.Lock()
+		}()
+
+		time.Sleep(10 * time.Millisecond)
+		for i := 1; i <= 4; i++ {
+			_, err = mmap.WriteAt([]byte{1}, int64(i*oneMB))
+			tt.AssertIsNotError(t, err)
+		}
+
+		select {
+		case <-locked:
+			t.Fatal("locked twice")
+		default:
+		}
+
+		tt.AssertIsNotError(t, mu.Unlock())
+		tt.AssertIsNotError(t, <-locked)
+		tt.AssertIsNotError(t, mu.Unlock())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		mmap, _ := newIpcMmap(t)
+		defer closeMmap(t, mmap)
+
+		_, err := mmap.Mutex(2)
+		tt.AssertEqual(t, ErrUnaligned, err)
+		_, err = mmap.Mutex(int64(mmap.Cap()))
+		tt.AssertEqual(t, ErrOverflow, err)
+	})
+
+	t.Run("read-only", func(t *testing.T) {
+		mmap, file := newIpcMmap(t)
+		defer closeMmap(t, mmap)
+
+		readOnly, err := New(NewReadOnly(file))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, readOnly)
+
+		_, err = readOnly.Mutex(ipcMutex)
+		tt.AssertEqual(t, ErrReadOnly, err)
+		_, err = readOnly.RWMutex(ipcRWMutex)
+		tt.AssertEqual(t, ErrReadOnly, err)
+		_, err = readOnly.Semaphore(ipcMutex)
+		tt.AssertEqual(t, ErrReadOnly, err)
+		_, err = readOnly.Cond(ipcMutex)
+		tt.AssertEqual(t, ErrReadOnly, err)
+
+		// a primitive made on a writable map fails the same way through a
+		// read-only one
+		_, err = mmap.Mutex(ipcMutex)
+		tt.AssertIsNotError(t, err)
+		mu := &Mutex{m: readOnly, off: ipcMutex}
+		tt.AssertEqual(t, ErrReadOnly, mu.Lock())
+	})
+}
+
+func TestRWMutex(t *testing.T) {
+	t.Run("readers-and-writer", func(t *testing.T) {
+		mmap, _ := newIpcMmap(t)
+		defer closeMmap(t, mmap)
+
+		rw, err := mmap.RWMutex(ipcRWMutex)
+		tt.AssertIsNotError(t, err)
+
+		tt.AssertIsNotError(t, rw.RLock())
+		tt.AssertIsNotError(t, rw.RLock())
+
+		written := make(chan error, 1)
+		go func() {
+			written <- rw.Lock()
+		}()
+
+		time.Sleep(10 * time.Millisecond)
+		tt.AssertIsNotError(t, rw.RUnlock())
+		select {
+		case <-written:
+			t.Fatal("writer locked beside a reader")
+		default:
+		}
+
+		tt.AssertIsNotError(t, rw.RUnlock())
+		tt.AssertIsNotError(t, <-written)
+
+		read := make(chan error, 1)
+		go func() {
+			read <- rw.RLock()
+		}()
+
+		time.Sleep(10 * time.Millisecond)
+		select {
+		case <-read:
+			t.Fatal("reader locked beside the writer")
+		default:
+		}
+
+		tt.AssertIsNotError(t, rw.Unlock())
+		tt.AssertIsNotError(t, <-read)
+		tt.AssertIsNotError(t, rw.RUnlock())
+		tt.AssertEqual(t, ErrNotLocked, rw.RUnlock())
+		tt.AssertEqual(t, ErrNotLocked, rw.Unlock())
+	})
+
+	t.Run("writer-dead", func(t *testing.T) {
+		mmap, file := newIpcMmap(t)
+		defer closeMmap(t, mmap)
+
+		rw, err := mmap.RWMutex(ipcRWMutex)
+		tt.AssertIsNotError(t, err)
+
+		tt.AssertIsNotError(t, runHelper("write-lock-and-die", file).Run())
+		tt.AssertEqual(t, ErrOwnerDead, rw.RLock())
+		tt.AssertIsNotError(t, rw.RUnlock())
+
+		tt.AssertIsNotError(t, runHelper("write-lock-and-die", file).Run())
+		tt.AssertEqual(t, ErrOwnerDead, rw.Lock())
+		tt.AssertIsNotError(t, rw.Unlock())
+	})
+}
+
+func TestSemaphore(t *testing.T) {
+	mmap, _ := newIpcMmap(t)
+	defer closeMmap(t, mmap)
+
+	sem, err := mmap.Semaphore(ipcRWMutex)
+	tt.AssertIsNotError(t, err)
+	tt.AssertIsNotError(t, sem.Init(2))
+
+	tt.AssertIsNotError(t, sem.Acquire())
+	acquired, err := sem.TryAcquire()
+	tt.AssertIsNotError(t, err)
+	tt.AssertTrue(t, acquired)
+	acquired, err = sem.TryAcquire()
+	tt.AssertIsNotError(t, err)
+	tt.AssertFalse(t, acquired)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- sem.Acquire()
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	select {
+	case <-done:
+		t.Fatal("acquired without a permit")
+	default:
+	}
+
+	tt.AssertIsNotError(t, sem.Release())
+	tt.AssertIsNotError(t, <-done)
+
+	tt.AssertEqual(t, ErrOverflow, sem.Init(1<<30))
+}
+
+func TestCond(t *testing.T) {
+	mmap, file := newIpcMmap(t)
+	defer closeMmap(t, mmap)
+
+	mu, err := mmap.Mutex(ipcMutex)
+	tt.AssertIsNotError(t, err)
+	cond, err := mmap.Cond(ipcCond)
+	tt.AssertIsNotError(t, err)
+
+	tt.AssertIsNotError(t, mu.Lock())
+
+	cmd := runHelper("signal", file)
+	tt.AssertIsNotError(t, cmd.Start())
+
+	for {
+		flag, err := mmap.AtomicLoadUint32(ipcFlag)
+		tt.AssertIsNotError(t, err)
+		if flag != 0 {
+			break
+		}
+		tt.AssertIsNotError(t, cond.Wait(mu))
+	}
+
+	tt.AssertIsNotError(t, mu.Unlock())
+	tt.AssertIsNotError(t, cmd.Wait())
+}
@@ -0,0 +1,105 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package mmap
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+type lockMode int
+
+const (
+	unlocked lockMode = iota
+	lockedAll
+	lockedOnFault
+)
+
+type shouldLock interface {
+	ShouldLock() (locked bool, onFault bool)
+}
+
+// Lock pins the whole mapping in RAM. The lock is kept across growth. If the
+// grown mapping cannot be locked, e.g. beyond RLIMIT_MEMLOCK, the map still
+// grows but is unlocked from then on, and the call that grew it returns the
+// error, a MemlockLimitError for the limit.
+func (m *Mmap) Lock() error {
+	return m.setLockMode(lockedAll)
+}
+
+// LockOnFault is like Lock, but pages are only pinned once they are touched
+// (mlock2 with MLOCK_ONFAULT). Where this is not available it falls back to
+// Lock.
+func (m *Mmap) LockOnFault() error {
+	return m.setLockMode(lockedOnFault)
+}
+
+func (m *Mmap) Unlock() error {
+	return m.setLockMode(unlocked)
+}
+
+// LockRange pins [off, off+length), extending the range down to the page
+// boundary. Unlike Lock, a range lock is not restored after the mapping is
+// replaced by growth.
+func (m *Mmap) LockRange(off int64, length int) error {
+	return m.lockRange(off, length, lockedAll)
+}
+
+func (m *Mmap) UnlockRange(off int64, length int) error {
+	return m.lockRange(off, length, unlocked)
+}
+
+func (m *Mmap) lockRange(off int64, length int, mode lockMode) error {
+	if err := m.rlock(0); err != nil {
+		return err
+	}
+	defer m.mu.RUnlock()
+
+	if off < 0 || length < 0 || off+int64(length) > int64(len(m.data)) {
+		return ErrOverflow
+	}
+
+	return m.mlock(int(off), int(off)+length, mode)
+}
+
+func (m *Mmap) setLockMode(mode lockMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrIsClosed
+	}
+
+	if err := m.mlock(0, len(m.data), mode); err != nil {
+		return err
+	}
+
+	m.locked = mode
+	return nil
+}
+
+func (m *Mmap) mlock(start, end int, mode lockMode) error {
+	if start == end {
+		return nil
+	}
+
+	b := m.pages(start, end)
+
+	var err error
+	switch mode {
+	case lockedAll:
+		err = unix.Mlock(b)
+	case lockedOnFault:
+		err = mlockOnFault(b)
+	default:
+		return unix.Munlock(b)
+	}
+
+	if err == unix.ENOMEM || err == unix.EAGAIN {
+		var limit unix.Rlimit
+		if unix.Getrlimit(unix.RLIMIT_MEMLOCK, &limit) == nil {
+			return &MemlockLimitError{Size: len(b), Limit: uint64(limit.Cur), Err: err}
+		}
+	}
+	return err
+}
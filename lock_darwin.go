@@ -0,0 +1,7 @@
+package mmap
+
+import "golang.org/x/sys/unix"
+
+func mlockOnFault(b []byte) error {
+	return unix.Mlock(b)
+}
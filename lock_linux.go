@@ -0,0 +1,18 @@
+package mmap
+
+import "golang.org/x/sys/unix"
+
+const _MLOCK_ONFAULT = 0x1
+
+func mlockOnFault(b []byte) error {
+	_, _, errno := unix.Syscall(unix.SYS_MLOCK2, addrOf(b), uintptr(len(b)), _MLOCK_ONFAULT)
+	switch errno {
+	case 0:
+		return nil
+	case unix.ENOSYS, unix.EINVAL:
+		// kernels before 4.4
+		return unix.Mlock(b)
+	default:
+		return errno
+	}
+}
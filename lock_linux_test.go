@@ -0,0 +1,98 @@
+package mmap
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ImSingee/tt"
+	"golang.org/x/sys/unix"
+)
+
+func TestLockSurvivesGrowth(t *testing.T) {
+	for name, mremap := range map[string]bool{"mremap": true, "remap": false} {
+		t.Run(name, func(t *testing.T) {
+			defer func(old bool) { canMremap = old }(canMremap)
+			canMremap = mremap
+
+			args := NewReadWrite("")
+			args.Locked = true
+
+			mmap, err := New(args)
+			tt.AssertIsNotError(t, err)
+			defer closeMmap(t, mmap)
+
+			tt.AssertTrue(t, hasVmFlag(t, mmap.data, "lo"))
+
+			_, err = mmap.WriteAt([]byte(HelloWorld), 4*oneMB)
+			tt.AssertIsNotError(t, err)
+			tt.AssertTrue(t, hasVmFlag(t, mmap.data, "lo"))
+
+			tt.AssertIsNotError(t, mmap.Unlock())
+			tt.AssertFalse(t, hasVmFlag(t, mmap.data, "lo"))
+
+			_, err = mmap.WriteAt([]byte(HelloWorld), 8*oneMB)
+			tt.AssertIsNotError(t, err)
+			tt.AssertFalse(t, hasVmFlag(t, mmap.data, "lo"))
+		})
+	}
+}
+
+func TestLockLimit(t *testing.T) {
+	var old unix.Rlimit
+	tt.AssertIsNotError(t, unix.Getrlimit(unix.RLIMIT_MEMLOCK, &old))
+
+	limit := old
+	limit.Cur = 64 * 1024
+	tt.AssertIsNotError(t, unix.Setrlimit(unix.RLIMIT_MEMLOCK, &limit))
+	defer func() { tt.AssertIsNotError(t, unix.Setrlimit(unix.RLIMIT_MEMLOCK, &old)) }()
+
+	mmap, err := New(NewReadWrite(""))
+	tt.AssertIsNotError(t, err)
+	defer closeMmap(t, mmap)
+
+	err = mmap.Lock()
+	if err == nil {
+		t.Skip("RLIMIT_MEMLOCK does not apply (CAP_IPC_LOCK)")
+	}
+
+	var limitErr *MemlockLimitError
+	tt.AssertTrue(t, errors.As(err, &limitErr))
+	tt.AssertEqual(t, uint64(64*1024), limitErr.Limit)
+	tt.AssertEqual(t, oneMB, limitErr.Size)
+	tt.AssertEqual(t, unlocked, mmap.locked)
+}
+
+func TestLockLimitOnGrowth(t *testing.T) {
+	var old unix.Rlimit
+	tt.AssertIsNotError(t, unix.Getrlimit(unix.RLIMIT_MEMLOCK, &old))
+
+	limit := old
+	limit.Cur = 64 * 1024
+	tt.AssertIsNotError(t, unix.Setrlimit(unix.RLIMIT_MEMLOCK, &limit))
+	defer func() { tt.AssertIsNotError(t, unix.Setrlimit(unix.RLIMIT_MEMLOCK, &old)) }()
+
+	f, err := newHelloWorldFile()
+	tt.AssertIsNotError(t, err)
+
+	args := NewReadWrite(f)
+	args.Locked = true
+
+	mmap, err := New(args)
+	tt.AssertIsNotError(t, err)
+	defer closeMmap(t, mmap)
+
+	err = mmap.EnsureCapacity(oneMB)
+	if err == nil {
+		t.Skip("RLIMIT_MEMLOCK does not apply (CAP_IPC_LOCK)")
+	}
+
+	// the map grew, but is no longer locked
+	var limitErr *MemlockLimitError
+	tt.AssertTrue(t, errors.As(err, &limitErr))
+	tt.AssertEqual(t, unlocked, mmap.locked)
+	tt.AssertTrue(t, mmap.Cap() >= oneMB)
+	tt.AssertFalse(t, hasVmFlag(t, mmap.data, "lo"))
+
+	_, err = mmap.WriteAt([]byte(HelloWorld), 2*oneMB)
+	tt.AssertIsNotError(t, err)
+}
@@ -0,0 +1,62 @@
+package mmap
+
+import (
+	"testing"
+
+	"github.com/ImSingee/tt"
+)
+
+func TestLock(t *testing.T) {
+	t.Run("simple", func(t *testing.T) {
+		mmap, err := New(NewReadWrite(""))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		tt.AssertIsNotError(t, mmap.Lock())
+		tt.AssertIsNotError(t, mmap.Unlock())
+		tt.AssertIsNotError(t, mmap.LockOnFault())
+		tt.AssertIsNotError(t, mmap.Unlock())
+
+		tt.AssertIsNotError(t, mmap.LockRange(5, 2*pageSize))
+		tt.AssertIsNotError(t, mmap.UnlockRange(5, 2*pageSize))
+	})
+
+	t.Run("locked-growth", func(t *testing.T) {
+		args := NewReadWrite("")
+		args.Locked = true
+
+		mmap, err := New(args)
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		_, err = mmap.WriteAt([]byte(HelloWorld), 2*oneMB)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, lockedAll, mmap.locked)
+
+		p, err := mmap.Bytes(2*oneMB, LenOfHelloWorld)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, HelloWorld, string(p))
+	})
+
+	t.Run("overflow", func(t *testing.T) {
+		f, err := newHelloWorldFile()
+		tt.AssertIsNotError(t, err)
+
+		mmap, err := New(NewReadOnly(f))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		tt.AssertEqual(t, ErrOverflow, mmap.LockRange(0, LenOfHelloWorld+1))
+		tt.AssertEqual(t, ErrOverflow, mmap.UnlockRange(-1, 1))
+	})
+
+	t.Run("lock-after-close", func(t *testing.T) {
+		mmap, err := New(NewReadWrite(""))
+		tt.AssertIsNotError(t, err)
+		closeMmap(t, mmap)
+
+		tt.AssertEqual(t, ErrIsClosed, mmap.Lock())
+		tt.AssertEqual(t, ErrIsClosed, mmap.Unlock())
+		tt.AssertEqual(t, ErrIsClosed, mmap.LockRange(0, 1))
+	})
+}
@@ -0,0 +1,319 @@
+package mmap
+
+import (
+	"io"
+	"sync/atomic"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// SharedLog is a circular log of records laid over a shared map, that any
+// number of processes append to and any number of consumers read from, each
+// at its own cursor.
+//
+// A producer reserves the space of a record with a fetch-add on the tail,
+// writes the record and publishes it by setting its commit word to its
+// position plus one, which is unique per lap. Consumers read records in the
+// order of their reservation, so a producer that dies between reservation
+// and commit stalls them. A consumer that falls behind by more than the
+// capacity has been lapped and skips ahead to the tail.
+//
+// The capacity is kept in the header, every process maps the whole log before
+// it touches it, so one process growing the file is picked up by the others.
+type SharedLog struct {
+	m        *Mmap
+	off      int64
+	capacity uint64
+}
+
+// logHeader is the layout of the first logHeaderSize bytes of the log, the
+// records follow it.
+type logHeader struct {
+	capacity uint64
+	tail     uint64 // end of the last reservation
+	seq      uint32 // bumped by every commit, consumers wait on it
+	waiters  uint32
+}
+
+const logHeaderSize = 64
+
+const (
+	logTail    = 8
+	logSeq     = 16
+	logWaiters = 20
+)
+
+// logFrame is the layout in front of every record, records take multiples of
+// logFrameSize so that every frame is aligned.
+type logFrame struct {
+	commit uint64 // position of the record plus one once it is published
+	length uint32
+	flags  uint32
+}
+
+const logFrameSize = 16
+
+// logPadding marks a frame that only fills space and is skipped by consumers
+const logPadding = 1
+
+// frameSize returns the space a record of length bytes takes
+func frameSize(length int) uint64 {
+	return uint64(logFrameSize+length+logFrameSize-1) &^ (logFrameSize - 1)
+}
+
+// SharedLog lays a log with capacity bytes of records over the map at off,
+// which must be 8 byte aligned. The capacity is rounded up to a multiple of
+// 16. A zero header is initialized, otherwise its capacity must match, so
+// others may pass capacity 0 to take it from the header. The map must be
+// shared.
+func (m *Mmap) SharedLog(off int64, capacity int) (*SharedLog, error) {
+	if m.args.Flags()&unix.MAP_SHARED == 0 {
+		return nil, ErrNotSupported
+	}
+	if capacity < 0 {
+		return nil, ErrOverflow
+	}
+	if capacity > 0 {
+		capacity = (capacity + logFrameSize - 1) &^ (logFrameSize - 1)
+		if err := m.EnsureCapacity(int(off) + logHeaderSize + capacity); err != nil {
+			return nil, err
+		}
+	}
+
+	stored, err := m.AtomicLoadUint64(off)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case stored == 0 && capacity == 0:
+		return nil, ErrBadHeader
+	case stored == 0:
+		if _, err := m.CompareAndSwapUint64(off, 0, uint64(capacity)); err != nil {
+			return nil, err
+		}
+		if stored, err = m.AtomicLoadUint64(off); err != nil {
+			return nil, err
+		}
+	}
+	if capacity != 0 && stored != uint64(capacity) {
+		return nil, ErrBadHeader
+	}
+
+	l := &SharedLog{m: m, off: off, capacity: stored}
+
+	// make the whole log part of this map and of Len
+	_, data, err := l.header()
+	if err != nil {
+		return nil, err
+	}
+	m.extend(int(off) + logHeaderSize + len(data))
+	m.mu.RUnlock()
+
+	return l, nil
+}
+
+// Cap returns the space for records.
+func (l *SharedLog) Cap() int {
+	return int(l.capacity)
+}
+
+// header read-locks the map, growing it to hold the whole log, and returns the
+// header and the records of the log. The caller must m.mu.RUnlock on success.
+func (l *SharedLog) header() (*logHeader, []byte, error) {
+	start := l.off + logHeaderSize
+	if err := l.m.rlock(int(start) + int(l.capacity)); err != nil {
+		return nil, nil, err
+	}
+
+	h := (*logHeader)(unsafe.Pointer(&l.m.data[l.off]))
+	return h, l.m.data[start : start+int64(l.capacity)], nil
+}
+
+// frameAt returns the frame at the position pos
+func (l *SharedLog) frameAt(data []byte, pos uint64) *logFrame {
+	return (*logFrame)(unsafe.Pointer(&data[pos%l.capacity]))
+}
+
+// Append appends p as a record and returns its position. A record with its
+// frame may take at most half the capacity, so that it fits in one piece after
+// wrapping around at most once.
+func (l *SharedLog) Append(p []byte) (pos uint64, err error) {
+	size := frameSize(len(p))
+	if size > l.capacity/2 {
+		return 0, ErrOverflow
+	}
+	if l.m.readOnly() {
+		return 0, ErrReadOnly
+	}
+
+	h, data, err := l.header()
+	if err != nil {
+		return 0, err
+	}
+	defer l.m.mu.RUnlock()
+
+	for {
+		pos = atomic.AddUint64(&h.tail, size) - size
+
+		start := pos % l.capacity
+		if start+size <= l.capacity {
+			break
+		}
+
+		// the record would run across the end of the log, fill the space up
+		// to the end and the rest of the reservation from the start with
+		// padding and reserve again, which now starts before size as the
+		// record takes at most half the log
+		head := l.capacity - start
+		l.publish(data, pos, logPadding, int(head-logFrameSize), nil)
+		l.publish(data, pos+head, logPadding, int(size-head-logFrameSize), nil)
+	}
+
+	l.publish(data, pos, 0, len(p), p)
+
+	atomic.AddUint32(&h.seq, 1)
+	if atomic.LoadUint32(&h.waiters) > 0 {
+		if _, err := futexWake(&h.seq, wakeAll); err != nil {
+			return pos, err
+		}
+	}
+	return pos, nil
+}
+
+// publish writes a frame with p at pos and commits it
+func (l *SharedLog) publish(data []byte, pos uint64, flags uint32, length int, p []byte) {
+	f := l.frameAt(data, pos)
+	f.length = uint32(length)
+	f.flags = flags
+
+	start := pos%l.capacity + logFrameSize
+	copy(data[start:], p)
+
+	atomic.StoreUint64(&f.commit, pos+1)
+}
+
+// Tail returns the position the next record will be appended at.
+func (l *SharedLog) Tail() (uint64, error) {
+	return l.m.AtomicLoadUint64(l.off + logTail)
+}
+
+// Consumer returns a consumer that reads records appended from now on.
+func (l *SharedLog) Consumer() (*LogConsumer, error) {
+	tail, err := l.Tail()
+	if err != nil {
+		return nil, err
+	}
+	return l.ConsumerAt(tail), nil
+}
+
+// ConsumerAt returns a consumer that reads from cursor on, a position that
+// Append or LogConsumer.Cursor returned.
+func (l *SharedLog) ConsumerAt(cursor uint64) *LogConsumer {
+	return &LogConsumer{l: l, cursor: cursor}
+}
+
+// LogConsumer reads the records of a SharedLog in order. It must not be used
+// by more than one goroutine at a time.
+type LogConsumer struct {
+	l      *SharedLog
+	cursor uint64
+}
+
+// Cursor returns the position of the next record to read.
+func (c *LogConsumer) Cursor() uint64 {
+	return c.cursor
+}
+
+// Read reads the next record into p, waiting for it to be published. A record
+// larger than p is not consumed and io.ErrShortBuffer is returned. If the
+// consumer was lapped, the cursor moves to the tail and ErrLapped is returned.
+func (c *LogConsumer) Read(p []byte) (n int, err error) {
+	for {
+		n, read, seq, err := c.tryRead(p)
+		if err != nil || read {
+			return n, err
+		}
+
+		if err := c.wait(seq); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// TryRead reads the next record into p like Read, or returns ErrEmpty if it is
+// not published yet.
+func (c *LogConsumer) TryRead(p []byte) (n int, err error) {
+	n, read, _, err := c.tryRead(p)
+	if err != nil || read {
+		return n, err
+	}
+	return 0, ErrEmpty
+}
+
+// tryRead reads the next record if it is published. Otherwise it returns the
+// commit sequence the consumer has to wait on.
+func (c *LogConsumer) tryRead(p []byte) (n int, read bool, seq uint32, err error) {
+	l := c.l
+	h, data, err := l.header()
+	if err != nil {
+		return 0, false, 0, err
+	}
+	defer l.m.mu.RUnlock()
+
+	for {
+		seq = atomic.LoadUint32(&h.seq)
+		tail := atomic.LoadUint64(&h.tail)
+		if tail-c.cursor > l.capacity {
+			c.cursor = tail
+			return 0, false, 0, ErrLapped
+		}
+
+		f := l.frameAt(data, c.cursor)
+		if c.cursor == tail || atomic.LoadUint64(&f.commit) != c.cursor+1 {
+			return 0, false, seq, nil
+		}
+
+		length, flags := int(f.length), f.flags
+		size := frameSize(length)
+		if size > l.capacity {
+			return 0, false, 0, ErrBadHeader
+		}
+
+		if flags&logPadding == 0 {
+			if length > len(p) {
+				return 0, false, 0, io.ErrShortBuffer
+			}
+
+			start := c.cursor%l.capacity + logFrameSize
+			n = copy(p, data[start:start+uint64(length)])
+		}
+
+		// a producer that reserved beyond the frame of the last lap may have
+		// overwritten the record while it was copied
+		if atomic.LoadUint64(&h.tail) > c.cursor+l.capacity {
+			c.cursor = atomic.LoadUint64(&h.tail)
+			return 0, false, 0, ErrLapped
+		}
+
+		c.cursor += size
+		if flags&logPadding == 0 {
+			return n, true, 0, nil
+		}
+	}
+}
+
+// wait waits for the commit sequence to move on from seq
+func (c *LogConsumer) wait(seq uint32) error {
+	l := c.l
+	if _, err := l.m.AtomicAddUint32(l.off+logWaiters, 1); err != nil {
+		return err
+	}
+
+	_, err := l.m.WaitUint32(l.off+logSeq, seq, -1)
+	if _, derr := l.m.AtomicAddUint32(l.off+logWaiters, ^uint32(0)); err == nil {
+		err = derr
+	}
+	return err
+}
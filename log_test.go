@@ -0,0 +1,316 @@
+package mmap
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ImSingee/tt"
+)
+
+// logRecord is the i-th record of a producer, of varying size
+func logRecord(producer, i int) []byte {
+	return []byte(fmt.Sprintf("%d:%d:%s", producer, i, strings.Repeat("x", i%61)))
+}
+
+// consumeLog reads count records and checks that the records of every
+// producer arrive in order
+func consumeLog(c *LogConsumer, producers, count int) error {
+	next := make([]int, producers)
+	p := make([]byte, 256)
+	for i := 0; i < count; i++ {
+		n, err := c.Read(p)
+		if err != nil {
+			return err
+		}
+
+		var producer, seq int
+		if _, err := fmt.Sscanf(string(p[:n]), "%d:%d:", &producer, &seq); err != nil {
+			return err
+		}
+		if seq != next[producer] || string(p[:n]) != string(logRecord(producer, seq)) {
+			return fmt.Errorf("producer %d: got record %d, expected %d", producer, seq, next[producer])
+		}
+		next[producer]++
+	}
+	return nil
+}
+
+func TestSharedLog(t *testing.T) {
+	t.Run("processes", func(t *testing.T) {
+		mmap, file := newIpcMmap(t)
+		defer closeMmap(t, mmap)
+
+		// large enough that nobody is lapped
+		log, err := mmap.SharedLog(ipcRing, 4*oneMB)
+		tt.AssertIsNotError(t, err)
+
+		const producers = 3
+		const consumers = 2
+
+		var consumed []*LogConsumer
+		for i := 0; i < consumers; i++ {
+			c, err := log.Consumer()
+			tt.AssertIsNotError(t, err)
+			consumed = append(consumed, c)
+		}
+
+		errs := make(chan error, consumers)
+		for _, c := range consumed {
+			go func(c *LogConsumer) {
+				errs <- consumeLog(c, producers, producers*helperMessages)
+			}(c)
+		}
+
+		var cmds []*exec.Cmd
+		for i := 0; i < producers; i++ {
+			cmd := runHelper("publish", file, fmt.Sprintf("MMAP_HELPER_PRODUCER=%d", i))
+			tt.AssertIsNotError(t, cmd.Start())
+			cmds = append(cmds, cmd)
+		}
+		for _, cmd := range cmds {
+			tt.AssertIsNotError(t, cmd.Wait())
+		}
+		for i := 0; i < consumers; i++ {
+			tt.AssertIsNotError(t, <-errs)
+		}
+	})
+
+	t.Run("goroutines-wrap", func(t *testing.T) {
+		mmap, _ := newIpcMmap(t)
+		defer closeMmap(t, mmap)
+
+		log, err := mmap.SharedLog(ipcRing, 64*1024)
+		tt.AssertIsNotError(t, err)
+
+		const producers = 4
+		const count = 2000
+
+		c, err := log.Consumer()
+		tt.AssertIsNotError(t, err)
+
+		// producers wait for the consumer so that it is never lapped
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, 200)
+		for i := 0; i < producers; i++ {
+			wg.Add(1)
+			go func(producer int) {
+				defer wg.Done()
+				for j := 0; j < count; j++ {
+					sem <- struct{}{}
+					if _, err := log.Append(logRecord(producer, j)); err != nil {
+						t.Error(err)
+						return
+					}
+				}
+			}(i)
+		}
+
+		next := make([]int, producers)
+		p := make([]byte, 256)
+		for i := 0; i < producers*count; i++ {
+			n, err := c.Read(p)
+			tt.AssertIsNotError(t, err)
+			<-sem
+
+			var producer, seq int
+			_, err = fmt.Sscanf(string(p[:n]), "%d:%d:", &producer, &seq)
+			tt.AssertIsNotError(t, err)
+			tt.AssertEqual(t, next[producer], seq)
+			next[producer]++
+		}
+		wg.Wait()
+
+		tail, err := log.Tail()
+		tt.AssertIsNotError(t, err)
+		tt.AssertTrue(t, tail > uint64(log.Cap()))
+		tt.AssertEqual(t, tail, c.Cursor())
+	})
+
+	t.Run("lapped", func(t *testing.T) {
+		mmap, _ := newIpcMmap(t)
+		defer closeMmap(t, mmap)
+
+		log, err := mmap.SharedLog(ipcRing, 1000)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, 1008, log.Cap())
+
+		c := log.ConsumerAt(0)
+		for i := 0; i < 100; i++ {
+			_, err := log.Append(logRecord(0, i))
+			tt.AssertIsNotError(t, err)
+		}
+
+		p := make([]byte, 256)
+		_, err = c.TryRead(p)
+		tt.AssertEqual(t, ErrLapped, err)
+
+		tail, err := log.Tail()
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, tail, c.Cursor())
+
+		_, err = c.TryRead(p)
+		tt.AssertEqual(t, ErrEmpty, err)
+
+		pos, err := log.Append([]byte(HelloWorld))
+		tt.AssertIsNotError(t, err)
+		n, err := c.TryRead(p)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, HelloWorld, string(p[:n]))
+
+		// the consumer may start at any position Append returned
+		n, err = log.ConsumerAt(pos).TryRead(p)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, HelloWorld, string(p[:n]))
+	})
+
+	t.Run("records", func(t *testing.T) {
+		mmap, _ := newIpcMmap(t)
+		defer closeMmap(t, mmap)
+
+		log, err := mmap.SharedLog(ipcRing, 64)
+		tt.AssertIsNotError(t, err)
+
+		_, err = log.Append(make([]byte, 49))
+		tt.AssertEqual(t, ErrOverflow, err)
+
+		// a record must fit in half the log, wherever the tail is
+		_, err = log.Append(make([]byte, 8))
+		tt.AssertIsNotError(t, err)
+		_, err = log.Append(make([]byte, 48))
+		tt.AssertEqual(t, ErrOverflow, err)
+		_, err = log.Append(make([]byte, 17))
+		tt.AssertEqual(t, ErrOverflow, err)
+		tail, err := log.Tail()
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, uint64(32), tail)
+
+		c, err := log.Consumer()
+		tt.AssertIsNotError(t, err)
+
+		_, err = log.Append([]byte(HelloWorld))
+		tt.AssertIsNotError(t, err)
+		_, err = c.TryRead(make([]byte, 4))
+		tt.AssertEqual(t, io.ErrShortBuffer, err)
+
+		n, err := c.TryRead(make([]byte, 16))
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, LenOfHelloWorld, n)
+
+		// an empty record
+		_, err = log.Append(nil)
+		tt.AssertIsNotError(t, err)
+		n, err = c.TryRead(nil)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, 0, n)
+	})
+
+	t.Run("wrap", func(t *testing.T) {
+		mmap, _ := newIpcMmap(t)
+		defer closeMmap(t, mmap)
+
+		log, err := mmap.SharedLog(ipcRing, 96)
+		tt.AssertIsNotError(t, err)
+
+		_, err = log.Append([]byte("first"))
+		tt.AssertIsNotError(t, err)
+		_, err = log.Append([]byte("second"))
+		tt.AssertIsNotError(t, err)
+
+		c, err := log.Consumer()
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, uint64(64), c.Cursor())
+
+		// 48 bytes do not fit in the 32 left before the end, padding fills
+		// them and the record starts over at the beginning
+		record := []byte(strings.Repeat("w", 32))
+		pos, err := log.Append(record)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, uint64(112), pos)
+
+		p := make([]byte, 64)
+		n, err := c.TryRead(p)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, string(record), string(p[:n]))
+		tt.AssertEqual(t, uint64(160), c.Cursor())
+
+		_, err = c.TryRead(p)
+		tt.AssertEqual(t, ErrEmpty, err)
+	})
+
+	t.Run("growth", func(t *testing.T) {
+		mmap, file := newIpcMmap(t)
+		defer closeMmap(t, mmap)
+
+		// mapped before the log grows the file
+		other, err := New(NewReadWrite(file))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, other)
+		tt.AssertEqual(t, oneMB, other.Cap())
+
+		log, err := mmap.SharedLog(ipcRing, 2*oneMB)
+		tt.AssertIsNotError(t, err)
+		pos, err := log.Append([]byte(HelloWorld))
+		tt.AssertIsNotError(t, err)
+
+		remote, err := other.SharedLog(ipcRing, 0)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, log.Cap(), remote.Cap())
+		tt.AssertTrue(t, other.Cap() >= ipcRing+logHeaderSize+2*oneMB)
+
+		p := make([]byte, 16)
+		n, err := remote.ConsumerAt(pos).TryRead(p)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, HelloWorld, string(p[:n]))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		mmap, _ := newIpcMmap(t)
+		defer closeMmap(t, mmap)
+
+		_, err := mmap.SharedLog(ipcRing, 0)
+		tt.AssertEqual(t, ErrBadHeader, err)
+
+		_, err = mmap.SharedLog(ipcRing, 1024)
+		tt.AssertIsNotError(t, err)
+		_, err = mmap.SharedLog(ipcRing, 2048)
+		tt.AssertEqual(t, ErrBadHeader, err)
+
+		args := NewReadWrite("")
+		args.Private = true
+		private, err := New(args)
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, private)
+
+		_, err = private.SharedLog(0, 1024)
+		tt.AssertEqual(t, ErrNotSupported, err)
+	})
+
+	t.Run("read-only", func(t *testing.T) {
+		mmap, file := newIpcMmap(t)
+		defer closeMmap(t, mmap)
+
+		log, err := mmap.SharedLog(ipcRing, 1024)
+		tt.AssertIsNotError(t, err)
+		_, err = log.Append([]byte(HelloWorld))
+		tt.AssertIsNotError(t, err)
+
+		readOnly, err := New(NewReadOnly(file))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, readOnly)
+
+		log, err = readOnly.SharedLog(ipcRing, 0)
+		tt.AssertIsNotError(t, err)
+		_, err = log.Append([]byte(HelloWorld))
+		tt.AssertEqual(t, ErrReadOnly, err)
+
+		// consumers only read
+		p := make([]byte, 16)
+		n, err := log.ConsumerAt(0).TryRead(p)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, HelloWorld, string(p[:n]))
+	})
+}
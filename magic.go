@@ -0,0 +1,172 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package mmap
+
+import (
+	"os"
+	"sync/atomic"
+
+	"golang.org/x/sys/unix"
+)
+
+// MagicRing is a byte ring buffer whose pages are mapped twice, back to back,
+// so that the readable and the writable part are always contiguous in memory,
+// even where they wrap around the end of the ring.
+//
+// One goroutine may write while another reads. The slices it hands out point
+// into the mapping and must not be used after Close, which must not race with
+// any other method.
+type MagicRing struct {
+	head uint64 // moved by CommitRead
+	tail uint64 // moved by CommitWrite
+
+	region []byte // both views, 2*size bytes
+	size   int
+}
+
+// NewMagicRing creates a ring of size bytes, rounded up to whole pages. It
+// lives in a memfd unless args names a file, then it takes size bytes of it
+// from args.FileOffset on, which must be page aligned. darwin cannot place
+// mappings and returns ErrNotSupported.
+func NewMagicRing(args *Args, size int) (*MagicRing, error) {
+	if size <= 0 {
+		return nil, ErrOverflow
+	}
+	size = alignPage(size)
+
+	f, off, err := magicFile(args, size)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	region, err := sysMmap(0, 2*size, unix.PROT_NONE, unix.MAP_PRIVATE|unix.MAP_ANON|unix.MAP_NORESERVE, -1, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for view := 0; view < 2; view++ {
+		_, err := sysMmap(addrOf(region)+uintptr(view*size), size, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED|unix.MAP_FIXED, int(f.Fd()), off)
+		if err != nil {
+			_ = sysMunmap(region)
+			return nil, err
+		}
+	}
+
+	return &MagicRing{region: region, size: size}, nil
+}
+
+// magicFile opens the file behind a ring and makes sure it holds size bytes
+// from the returned offset on.
+func magicFile(args *Args, size int) (*os.File, int64, error) {
+	if args == nil {
+		f, err := memfd(size)
+		return f, 0, err
+	}
+
+	switch {
+	case args.Readonly:
+		return nil, 0, ErrReadOnly
+	case args.Private:
+		// the two views would diverge as soon as one is written
+		return nil, 0, ErrNotSupported
+	case args.Offset()%int64(pageSize) != 0:
+		return nil, 0, ErrUnaligned
+	}
+
+	if err := args.Clean(); err != nil {
+		return nil, 0, err
+	}
+
+	f, err := args.Open()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, 0, err
+	}
+
+	if end := args.Offset() + int64(size); stat.Size() < end {
+		if err := unix.Ftruncate(int(f.Fd()), end); err != nil {
+			_ = f.Close()
+			return nil, 0, err
+		}
+	}
+
+	return f, args.Offset(), nil
+}
+
+// Cap returns the size of the ring.
+func (r *MagicRing) Cap() int {
+	return r.size
+}
+
+// Len returns the number of readable bytes.
+func (r *MagicRing) Len() int {
+	return int(atomic.LoadUint64(&r.tail) - atomic.LoadUint64(&r.head))
+}
+
+// ReadableSlice returns everything written and not read yet, in one piece.
+func (r *MagicRing) ReadableSlice() []byte {
+	if r.region == nil {
+		return nil
+	}
+
+	head := atomic.LoadUint64(&r.head)
+	start := int(head % uint64(r.size))
+	end := start + int(atomic.LoadUint64(&r.tail)-head)
+	return r.region[start:end:end]
+}
+
+// WritableSlice returns all free space of the ring, in one piece.
+func (r *MagicRing) WritableSlice() []byte {
+	if r.region == nil {
+		return nil
+	}
+
+	tail := atomic.LoadUint64(&r.tail)
+	start := int(tail % uint64(r.size))
+	end := start + r.size - int(tail-atomic.LoadUint64(&r.head))
+	return r.region[start:end:end]
+}
+
+// CommitWrite makes the first n bytes of WritableSlice readable.
+func (r *MagicRing) CommitWrite(n int) error {
+	if r.region == nil {
+		return ErrIsClosed
+	}
+	if n < 0 || n > r.size-r.Len() {
+		return ErrOverflow
+	}
+
+	atomic.AddUint64(&r.tail, uint64(n))
+	return nil
+}
+
+// CommitRead frees the first n bytes of ReadableSlice.
+func (r *MagicRing) CommitRead(n int) error {
+	if r.region == nil {
+		return ErrIsClosed
+	}
+	if n < 0 || n > r.Len() {
+		return ErrOverflow
+	}
+
+	atomic.AddUint64(&r.head, uint64(n))
+	return nil
+}
+
+// Close unmaps the ring.
+func (r *MagicRing) Close() error {
+	if r.region == nil {
+		return nil
+	}
+
+	err := sysMunmap(r.region)
+	r.region = nil
+	return err
+}
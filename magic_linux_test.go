@@ -0,0 +1,117 @@
+package mmap
+
+import (
+	"bytes"
+	"io/ioutil"
+	"runtime"
+	"testing"
+
+	"github.com/ImSingee/tt"
+)
+
+func TestMagicRing(t *testing.T) {
+	t.Run("wrap", func(t *testing.T) {
+		ring, err := NewMagicRing(nil, 1)
+		tt.AssertIsNotError(t, err)
+		defer ring.Close()
+
+		tt.AssertEqual(t, pageSize, ring.Cap())
+		tt.AssertEqual(t, pageSize, len(ring.WritableSlice()))
+		tt.AssertEqual(t, 0, len(ring.ReadableSlice()))
+
+		tt.AssertIsNotError(t, ring.CommitWrite(pageSize-10))
+		tt.AssertIsNotError(t, ring.CommitRead(pageSize-10))
+
+		// the free space runs across the end of the ring in one piece
+		w := ring.WritableSlice()
+		tt.AssertEqual(t, pageSize, len(w))
+		message := bytes.Repeat([]byte("0123456789"), 10)
+		copy(w, message)
+		tt.AssertIsNotError(t, ring.CommitWrite(len(message)))
+
+		tt.AssertEqual(t, len(message), ring.Len())
+		tt.AssertEqual(t, message, ring.ReadableSlice())
+
+		// both views share the pages
+		tt.AssertEqual(t, ring.region[:90], ring.region[pageSize:pageSize+90])
+
+		tt.AssertEqual(t, ErrOverflow, ring.CommitRead(len(message)+1))
+		tt.AssertEqual(t, ErrOverflow, ring.CommitWrite(pageSize))
+		tt.AssertEqual(t, ErrOverflow, ring.CommitWrite(-1))
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		ring, err := NewMagicRing(nil, 4*pageSize)
+		tt.AssertIsNotError(t, err)
+		defer ring.Close()
+
+		const total = 8 * oneMB
+		pattern := func(i int) byte { return byte(i % 251) }
+
+		go func() {
+			for written := 0; written < total; {
+				w := ring.WritableSlice()
+				if n := total - written; len(w) > n {
+					w = w[:n]
+				}
+				for i := range w {
+					w[i] = pattern(written + i)
+				}
+				_ = ring.CommitWrite(len(w))
+				written += len(w)
+				runtime.Gosched()
+			}
+		}()
+
+		for read := 0; read < total; {
+			r := ring.ReadableSlice()
+			for i, b := range r {
+				if b != pattern(read+i) {
+					t.Fatalf("byte %d is %d", read+i, b)
+				}
+			}
+			tt.AssertIsNotError(t, ring.CommitRead(len(r)))
+			read += len(r)
+			runtime.Gosched()
+		}
+	})
+
+	t.Run("file", func(t *testing.T) {
+		args := NewReadWrite("")
+		args.FileOffset = int64(pageSize)
+
+		ring, err := NewMagicRing(args, pageSize)
+		tt.AssertIsNotError(t, err)
+
+		copy(ring.WritableSlice(), HelloWorld)
+		tt.AssertIsNotError(t, ring.CommitWrite(LenOfHelloWorld))
+		tt.AssertIsNotError(t, ring.Close())
+		tt.AssertEqual(t, 0, len(ring.ReadableSlice()))
+		tt.AssertEqual(t, ErrIsClosed, ring.CommitRead(0))
+
+		p, err := ioutil.ReadFile(args.File)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, 2*pageSize, len(p))
+		tt.AssertEqual(t, HelloWorld, string(p[pageSize:pageSize+LenOfHelloWorld]))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := NewMagicRing(nil, 0)
+		tt.AssertEqual(t, ErrOverflow, err)
+
+		args := NewReadWrite("")
+		args.FileOffset = 1
+		_, err = NewMagicRing(args, pageSize)
+		tt.AssertEqual(t, ErrUnaligned, err)
+
+		args = NewReadWrite("")
+		args.Private = true
+		_, err = NewMagicRing(args, pageSize)
+		tt.AssertEqual(t, ErrNotSupported, err)
+
+		f, err := newHelloWorldFile()
+		tt.AssertIsNotError(t, err)
+		_, err = NewMagicRing(NewReadOnly(f), pageSize)
+		tt.AssertEqual(t, ErrReadOnly, err)
+	})
+}
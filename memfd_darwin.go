@@ -0,0 +1,7 @@
+package mmap
+
+import "os"
+
+func memfd(size int) (*os.File, error) {
+	return nil, ErrNotSupported
+}
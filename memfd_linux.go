@@ -0,0 +1,23 @@
+package mmap
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// memfd creates an anonymous file of size bytes
+func memfd(size int) (*os.File, error) {
+	fd, err := unix.MemfdCreate("mmap", unix.MFD_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+
+	f := os.NewFile(uintptr(fd), "memfd:mmap")
+	if err := unix.Ftruncate(fd, int64(size)); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return f, nil
+}
@@ -4,6 +4,10 @@
 package mmap
 
 import (
+	"os"
+	"sync"
+	"sync/atomic"
+
 	"golang.org/x/sys/unix"
 )
 
@@ -21,49 +25,253 @@ func New(args Opener) (*Mmap, error) {
 		closed: true,
 	}
 
-	return m, m.open(args.InitialSize())
+	if args.Offset() < 0 {
+		return m, ErrOverflow
+	}
+	if m.defaultAdvice().destructive() {
+		return m, ErrNotSupported
+	}
+
+	if args, ok := args.(shouldShareHeader); ok && args.ShouldShareHeader() {
+		m.header = true
+	}
+	if m.header && args.Offset()%8 != 0 {
+		return m, ErrUnaligned
+	}
+	m.slack = int(args.Offset()%int64(pageSize)) + m.headerSize()
+
+	if args, ok := args.(shouldLock); ok {
+		if locked, onFault := args.ShouldLock(); locked && onFault {
+			m.locked = lockedOnFault
+		} else if locked {
+			m.locked = lockedAll
+		}
+	}
+
+	f, err := args.Open()
+	if err != nil {
+		return m, err
+	}
+	m.file = f
+
+	withCap := args.InitialSize()
+	var size int64
+	if m.header {
+		size, withCap, err = m.openShared(withCap)
+	} else {
+		size, err = m.open(withCap)
+	}
+	if err != nil {
+		_ = m.closeFile()
+		return m, err
+	}
+	if err := m.restore(); err != nil {
+		_ = m.close()
+		_ = m.closeFile()
+		return m, err
+	}
+
+	// everything already in the window counts as written
+	size -= m.offset()
+	if size > int64(withCap) {
+		size = int64(withCap)
+	} else if size < 0 {
+		size = 0
+	}
+	m.length = size
+
+	return m, nil
 }
 
+// Mmap is safe for concurrent use. Accesses hold mu for reading while they
+// touch data, remapping (growth and close) holds it for writing so that it
+// waits for every in-flight access before the old mapping goes away.
 type Mmap struct {
+	// length is the logical size, the high-water mark of all writes. It is
+	// updated atomically since writers only hold mu for reading.
+	length int64
+
 	args Opener
 	grow Grower
 
-	data   []byte
-	closed bool
+	// file is the backing file, open from New until Close so that growth
+	// extends the same file even if the path now names another one. It is nil
+	// for an anonymous map.
+	file *os.File
+
+	// data is the window of the file starting at offset(). It lies slack
+	// bytes into the page aligned region that is really mapped, that is
+	// either mapping or, with a reservation, reserved. A shared header is
+	// part of the slack.
+	mu       sync.RWMutex
+	data     []byte
+	mapping  []byte
+	reserved []byte
+	slack    int
+	locked   lockMode
+	closed   bool
+
+	// header is set for a shared header, gen is the generation of the
+	// header that data was last mapped for
+	header bool
+	gen    uint64
 }
 
 func (m *Mmap) Cap() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	return cap(m.data)
 }
 
-func (m *Mmap) open(withCap int) (err error) {
-	f, err := m.args.Open()
-	if err != nil {
-		return err
+// Len returns the logical length of the map, that is the initial size of the
+// file or the end of the furthest write, whichever is larger.
+func (m *Mmap) Len() int {
+	return int(atomic.LoadInt64(&m.length))
+}
+
+func (m *Mmap) extend(end int) {
+	for {
+		length := atomic.LoadInt64(&m.length)
+		if int64(end) <= length || atomic.CompareAndSwapInt64(&m.length, length, int64(end)) {
+			return
+		}
+	}
+}
+
+// rlock read-locks the mapping after making sure it holds at least size
+// bytes, growing it otherwise. The caller must m.mu.RUnlock on success.
+func (m *Mmap) rlock(size int) error {
+	for {
+		m.mu.RLock()
+		if m.closed {
+			m.mu.RUnlock()
+			return ErrIsClosed
+		}
+		if m.stale() {
+			m.mu.RUnlock()
+			if err := m.Refresh(); err != nil {
+				return err
+			}
+			continue
+		}
+		if size <= len(m.data) {
+			return nil
+		}
+		m.mu.RUnlock()
+
+		if err := m.EnsureCapacity(size); err != nil {
+			return err
+		}
 	}
-	defer f.Close()
+}
+
+// readOnly reports whether the mapping lacks PROT_WRITE, storing to it would
+// fault
+func (m *Mmap) readOnly() bool {
+	return m.args.Prot()&unix.PROT_WRITE == 0
+}
+
+// raw returns the page aligned region data lives in
+func (m *Mmap) raw() []byte {
+	if m.reserved != nil {
+		return m.reserved
+	}
+	return m.mapping
+}
 
-	stat, err := f.Stat()
+// pages returns data[start:end] extended down to the page boundary, as
+// required by msync, madvise and friends
+func (m *Mmap) pages(start, end int) []byte {
+	return m.raw()[(m.slack+start)&^(pageSize-1) : m.slack+end]
+}
+
+// setData points data at the first size bytes behind the slack of raw
+func (m *Mmap) setData(raw []byte, size int) {
+	m.data = raw[m.slack : m.slack+size : m.slack+size]
+}
+
+// offset is where data starts in the file
+func (m *Mmap) offset() int64 {
+	return m.args.Offset() + int64(m.headerSize())
+}
+
+// fileOffset is the page aligned offset the mapping starts at in the file
+func (m *Mmap) fileOffset() int64 {
+	return m.offset() - int64(m.slack)
+}
+
+// File returns the backing file, which stays open until Close, or nil for an
+// anonymous map. It must not be closed.
+func (m *Mmap) File() *os.File {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.file
+}
+
+// Fd returns the descriptor of File, or -1 for an anonymous or closed map.
+func (m *Mmap) Fd() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return fdOf(m.file)
+}
+
+// extendFile extends the backing file so that the window holds at least
+// withCap bytes, returning the file size from before. A file that is already
+// large enough is never truncated.
+func (m *Mmap) extendFile(withCap int) (int64, error) {
+	if m.file == nil {
+		return 0, nil
+	}
+
+	stat, err := m.file.Stat()
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	size := stat.Size()
-	if size < int64(withCap) {
-		err := unix.Ftruncate(int(f.Fd()), int64(withCap))
-		if err != nil {
-			return err
+	if end := m.offset() + int64(withCap); size < end {
+		if err := unix.Ftruncate(int(m.file.Fd()), end); err != nil {
+			return 0, err
 		}
 	}
 
-	m.data, err = unix.Mmap(int(f.Fd()), m.args.Offset(), withCap, m.args.Prot(), m.args.Flags())
-	if err == nil {
+	return size, nil
+}
+
+// open maps the file with withCap bytes and returns the size the file had
+// before it was extended to that.
+func (m *Mmap) open(withCap int) (size int64, err error) {
+	if size, err = m.extendFile(withCap); err != nil {
+		return 0, err
+	}
+
+	if m.reservation() > 0 {
+		return size, m.openReserved(withCap)
+	}
+
+	if m.slack+withCap == 0 {
+		m.mapping, m.data = nil, nil
 		m.closed = false
+		return size, nil
 	}
-	return
+
+	m.mapping, err = sysMmap(0, m.slack+withCap, m.args.Prot(), m.args.Flags(), fdOf(m.file), m.fileOffset())
+	if err != nil {
+		return 0, err
+	}
+
+	m.setData(m.mapping, withCap)
+	m.closed = false
+	return size, nil
 }
 
 func (m *Mmap) IsClosed() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	return m.closed
 }
 
@@ -72,28 +280,148 @@ func (m *Mmap) close() (err error) {
 		return nil
 	}
 
-	err = unix.Munmap(m.data)
+	if m.reserved != nil {
+		err = sysMunmap(m.reserved)
+		m.reserved = nil
+	} else if m.mapping != nil {
+		err = sysMunmap(m.mapping)
+		m.mapping = nil
+	}
 	m.closed = true
 
 	return
 }
 
+// closeFile closes the backing file once the map is gone for good, close
+// alone keeps it for mapping it again.
+func (m *Mmap) closeFile() error {
+	if m.file == nil {
+		return nil
+	}
+
+	err := m.file.Close()
+	m.file = nil
+	return err
+}
+
+// remap resizes the mapping to newCap and restores its state. If the lock
+// cannot be restored, the mapping still has the new size but is left unlocked.
+func (m *Mmap) remap(newCap int) error {
+	if err := m.resize(newCap); err != nil {
+		return err
+	}
+
+	return m.restore()
+}
+
+// restore reapplies the state the kernel keeps per mapping, which is lost
+// whenever resize creates a new one.
+func (m *Mmap) restore() error {
+	if len(m.data) == 0 {
+		return nil
+	}
+
+	if advice := m.defaultAdvice(); advice != AdviceNormal {
+		if err := m.advise(0, len(m.data), advice); err != nil {
+			return err
+		}
+	}
+
+	if m.locked != unlocked {
+		if err := m.mlock(0, len(m.data), m.locked); err != nil {
+			// an mremap in place may have kept part of it locked
+			_ = m.mlock(0, len(m.data), unlocked)
+			m.locked = unlocked
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resize changes the size of the mapping. Where mremap is available the
+// mapping is resized in place so already faulted pages stay resident,
+// otherwise (or if it fails) the file is unmapped and mapped again.
+func (m *Mmap) resize(newCap int) error {
+	if m.reserved != nil {
+		return m.remapReserved(newCap)
+	}
+
+	if m.slack+newCap == 0 {
+		// an empty mapping cannot exist, keep the map open without one
+		if m.mapping != nil {
+			if err := sysMunmap(m.mapping); err != nil {
+				return err
+			}
+		}
+		m.mapping, m.data = nil, nil
+		return nil
+	}
+
+	// mremap cannot grow the shmem object behind a shared anonymous mapping
+	sharedAnonymous := m.anonymous() && m.args.Flags()&unix.MAP_SHARED != 0
+
+	if canMremap && m.mapping != nil && !sharedAnonymous {
+		if err := m.mremap(newCap); err == nil {
+			return nil
+		}
+	}
+
+	if m.anonymous() {
+		return m.reallocate(newCap)
+	}
+
+	return m.reOpen(newCap)
+}
+
+func (m *Mmap) mremap(newCap int) error {
+	if _, err := m.extendFile(newCap); err != nil {
+		return err
+	}
+
+	mapping, err := sysMremap(m.mapping, m.slack+newCap)
+	if err != nil {
+		return err
+	}
+
+	m.mapping = mapping
+	m.setData(mapping, newCap)
+	return nil
+}
+
 func (m *Mmap) reOpen(newCap int) error {
+	// never drop dirty pages on the floor when remapping
+	if err := m.flush(0, len(m.data), SyncWait); err != nil {
+		return err
+	}
+
 	if err := m.close(); err != nil {
 		return err
 	}
 
-	return m.open(newCap)
+	_, err := m.open(newCap)
+	return err
 }
 
 func (m *Mmap) EnsureCapacity(size int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if m.closed {
 		return ErrIsClosed
 	}
 
-	if capacity := m.Cap(); size > capacity {
-		next := m.grow(capacity, size)
-		if err := m.reOpen(next); err != nil {
+	if capacity := len(m.data); size > capacity {
+		if m.header {
+			return m.growShared(size, false)
+		}
+
+		next, err := m.next(capacity, size)
+		if err != nil {
+			return err
+		}
+
+		if err := m.remap(next); err != nil {
 			return err
 		}
 	}
@@ -101,6 +429,90 @@ func (m *Mmap) EnsureCapacity(size int) error {
 	return nil
 }
 
+// next asks the Grower for the capacity to grow to, clamped to the
+// reservation
+func (m *Mmap) next(capacity, size int) (int, error) {
+	next := m.grow(capacity, size)
+	if reserved := m.reservation(); m.reserved != nil && next > reserved {
+		if size > reserved {
+			return 0, ErrReservationExceeded
+		}
+		next = reserved
+	}
+	return next, nil
+}
+
+// Close unmaps the file and closes it. With Args.TrimOnClose a writable
+// shared map also truncates the file to Len, unless other processes rely on
+// its size through a shared header.
 func (m *Mmap) Close() error {
-	return m.close()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.trimFile(); err != nil {
+		_ = m.close()
+		_ = m.closeFile()
+		return err
+	}
+
+	if err := m.close(); err != nil {
+		_ = m.closeFile()
+		return err
+	}
+	return m.closeFile()
+}
+
+type shouldTrimOnClose interface {
+	ShouldTrimOnClose() bool
+}
+
+func (m *Mmap) trimFile() error {
+	if args, ok := m.args.(shouldTrimOnClose); !ok || !args.ShouldTrimOnClose() {
+		return nil
+	}
+	if m.closed || m.header || m.anonymous() || m.args.Prot()&unix.PROT_WRITE == 0 || m.args.Flags()&unix.MAP_SHARED == 0 {
+		return nil
+	}
+
+	length := atomic.LoadInt64(&m.length)
+	if length >= int64(len(m.data)) {
+		return nil
+	}
+
+	if owns, err := m.ownsTail(); err != nil || !owns {
+		return err
+	}
+
+	return unix.Ftruncate(int(m.file.Fd()), m.offset()+length)
+}
+
+// ownsTail reports whether the window reaches the end of the file, only then
+// it is allowed to make the file shorter.
+func (m *Mmap) ownsTail() (bool, error) {
+	stat, err := m.file.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	return stat.Size() <= m.offset()+int64(len(m.data)), nil
+}
+
+// dupFd duplicates the descriptor of the backing file for copies that may
+// block, which then need not hold mu to keep Close from closing it under them.
+// The caller must close it.
+func (m *Mmap) dupFd() (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return -1, ErrIsClosed
+	}
+	return unix.Dup(int(m.file.Fd()))
+}
+
+func fdOf(f *os.File) int {
+	if f == nil {
+		return -1
+	}
+	return int(f.Fd())
 }
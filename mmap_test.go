@@ -1,10 +1,13 @@
 package mmap
 
 import (
+	"bytes"
 	"errors"
+	"io"
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/ImSingee/tt"
 )
@@ -89,6 +92,250 @@ func TestMmapNewReadWrite(t *testing.T) {
 	tt.AssertEqual(t, expect, p)
 }
 
+func TestLen(t *testing.T) {
+	t.Run("fresh", func(t *testing.T) {
+		args := NewReadWrite("")
+		args.TrimOnClose = true
+
+		mmap, err := New(args)
+		tt.AssertIsNotError(t, err)
+
+		f := mmap.args.(*Args).File
+
+		tt.AssertEqual(t, 0, mmap.Len())
+		tt.AssertEqual(t, DefaultInitLength, mmap.Cap())
+
+		_, err = mmap.WriteAt([]byte(HelloWorld), 0)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, LenOfHelloWorld, mmap.Len())
+
+		buf := &bytes.Buffer{}
+		n, err := mmap.WriteTo(buf)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, int64(LenOfHelloWorld), n)
+		tt.AssertEqual(t, HelloWorld, buf.String())
+
+		closeMmap(t, mmap)
+		tt.AssertEqual(t, int64(LenOfHelloWorld), fileSize(f))
+
+		mmap, err = New(NewReadWrite(f))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		tt.AssertEqual(t, LenOfHelloWorld, mmap.Len())
+		tt.AssertEqual(t, LenOfHelloWorld, mmap.Cap())
+	})
+
+	t.Run("existing", func(t *testing.T) {
+		f, err := newHelloWorldFile()
+		tt.AssertIsNotError(t, err)
+
+		mmap, err := New(NewReadOnly(f))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		tt.AssertEqual(t, LenOfHelloWorld, mmap.Len())
+	})
+
+	t.Run("high-water-mark", func(t *testing.T) {
+		mmap, err := New(NewReadWrite(""))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		_, err = mmap.WriteAt([]byte(HelloWorld), 100)
+		tt.AssertIsNotError(t, err)
+		_, err = mmap.WriteAt([]byte(HelloWorld), 0)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, 100+LenOfHelloWorld, mmap.Len())
+
+		p, err := mmap.Bytes(90, 100)
+		tt.AssertEqual(t, io.EOF, err)
+		tt.AssertEqual(t, 10+LenOfHelloWorld, len(p))
+
+		tt.AssertIsNotError(t, mmap.Copy(0, 200, LenOfHelloWorld))
+		tt.AssertEqual(t, 200+LenOfHelloWorld, mmap.Len())
+	})
+
+	t.Run("private-keeps-file", func(t *testing.T) {
+		f, err := newHelloWorldFile()
+		tt.AssertIsNotError(t, err)
+
+		args := NewReadWrite(f)
+		args.InitLength = oneMB
+		args.Private = true
+
+		mmap, err := New(args)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, LenOfHelloWorld, mmap.Len())
+		closeMmap(t, mmap)
+
+		tt.AssertEqual(t, int64(oneMB), fileSize(f))
+	})
+
+	t.Run("close-keeps-file", func(t *testing.T) {
+		m1, err := New(NewReadWrite(""))
+		tt.AssertIsNotError(t, err)
+
+		f := m1.File().Name()
+		m2, err := New(NewReadWrite(f))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, m2)
+
+		_, err = m2.WriteAt([]byte(HelloWorld), 4096)
+		tt.AssertIsNotError(t, err)
+
+		// m1 knows nothing about what m2 wrote behind its Len
+		closeMmap(t, m1)
+		tt.AssertEqual(t, int64(DefaultInitLength), fileSize(f))
+
+		p, err := m2.Bytes(4096, LenOfHelloWorld)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, HelloWorld, string(p))
+	})
+}
+
+func TestWindow(t *testing.T) {
+	content := make([]byte, 3*pageSize+100)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	off := pageSize + 10
+
+	newFile := func(t *testing.T) string {
+		f, err := ioutil.TempFile("", "")
+		tt.AssertIsNotError(t, err)
+		defer f.Close()
+
+		_, err = f.Write(content)
+		tt.AssertIsNotError(t, err)
+		return f.Name()
+	}
+
+	t.Run("read", func(t *testing.T) {
+		args := NewReadOnly(newFile(t))
+		args.FileOffset = int64(off)
+		args.InitLength = 100
+
+		mmap, err := New(args)
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		tt.AssertEqual(t, 100, mmap.Cap())
+		tt.AssertEqual(t, 100, mmap.Len())
+
+		p := make([]byte, 100)
+		n, err := mmap.ReadAt(p, 0)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, 100, n)
+		tt.AssertEqual(t, content[off:off+100], p)
+	})
+
+	t.Run("rest-of-file", func(t *testing.T) {
+		args := NewReadOnly(newFile(t))
+		args.FileOffset = int64(off)
+
+		mmap, err := New(args)
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		tt.AssertEqual(t, len(content)-off, mmap.Len())
+
+		buf := &bytes.Buffer{}
+		_, err = mmap.WriteTo(buf)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, content[off:], buf.Bytes())
+	})
+
+	for name, mremap := range map[string]bool{"write-and-grow": true, "write-and-grow-without-mremap": false} {
+		mremap := mremap
+
+		t.Run(name, func(t *testing.T) {
+			defer func(old bool) { canMremap = old }(canMremap)
+			canMremap = canMremap && mremap
+
+			f := newFile(t)
+			args := NewReadWrite(f)
+			args.FileOffset = int64(off)
+			args.InitLength = 100
+			args.TrimOnClose = true
+
+			mmap, err := New(args)
+			tt.AssertIsNotError(t, err)
+
+			_, err = mmap.WriteAt([]byte(HelloWorld), 0)
+			tt.AssertIsNotError(t, err)
+
+			// grows into the rest of the file and beyond
+			_, err = mmap.WriteAt([]byte(HelloWorld), int64(3*pageSize))
+			tt.AssertIsNotError(t, err)
+			tt.AssertEqual(t, oneMB, mmap.Cap())
+
+			p, err := mmap.Bytes(100, 10)
+			tt.AssertIsNotError(t, err)
+			tt.AssertEqual(t, content[off+100:off+110], p)
+
+			tt.AssertIsNotError(t, mmap.Flush())
+			closeMmap(t, mmap)
+
+			expect := append([]byte{}, content...)
+			copy(expect[off:], HelloWorld)
+			expect = append(expect, make([]byte, off+3*pageSize+LenOfHelloWorld-len(expect))...)
+			copy(expect[off+3*pageSize:], HelloWorld)
+
+			p, err = ioutil.ReadFile(f)
+			tt.AssertIsNotError(t, err)
+			tt.AssertEqual(t, expect, p)
+		})
+	}
+
+	t.Run("never-truncate-larger-file", func(t *testing.T) {
+		f := newFile(t)
+		args := NewReadWrite(f)
+		args.FileOffset = int64(off)
+		args.InitLength = 100
+
+		mmap, err := New(args)
+		tt.AssertIsNotError(t, err)
+
+		tt.AssertIsNotError(t, mmap.Truncate(10))
+		tt.AssertEqual(t, 10, mmap.Cap())
+		tt.AssertEqual(t, int64(len(content)), fileSize(f))
+
+		closeMmap(t, mmap)
+		tt.AssertEqual(t, int64(len(content)), fileSize(f))
+	})
+
+	t.Run("beyond-end-of-file", func(t *testing.T) {
+		f, err := newHelloWorldFile()
+		tt.AssertIsNotError(t, err)
+
+		args := NewReadWrite(f)
+		args.FileOffset = 5000
+		args.InitLength = 100
+		args.TrimOnClose = true
+
+		mmap, err := New(args)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, 0, mmap.Len())
+		tt.AssertEqual(t, int64(5100), fileSize(f))
+
+		_, err = mmap.WriteAt([]byte(HelloWorld), 0)
+		tt.AssertIsNotError(t, err)
+
+		// the window reaches the end of file, so it is trimmed to Len
+		closeMmap(t, mmap)
+		tt.AssertEqual(t, int64(5000+LenOfHelloWorld), fileSize(f))
+	})
+
+	t.Run("negative", func(t *testing.T) {
+		args := NewReadOnly(newFile(t))
+		args.FileOffset = -1
+
+		_, err := New(args)
+		tt.AssertEqual(t, ErrOverflow, err)
+	})
+}
+
 func TestNotExistFile(t *testing.T) {
 	t.Run("not-exist-readonly", func(t *testing.T) {
 		mmap, err := New(&Args{
@@ -127,6 +374,21 @@ func closeMmap(t *testing.T, m *Mmap) {
 	tt.AssertIsNotError(t, m.Close())
 }
 
+// within fails the test unless f returns in time
+func within(t *testing.T, f func() error) {
+	t.Helper()
+
+	done := make(chan error, 1)
+	go func() { done <- f() }()
+
+	select {
+	case err := <-done:
+		tt.AssertIsNotError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("blocked")
+	}
+}
+
 func newHelloWorldFile() (string, error) {
 	d, err := ioutil.TempDir("", "")
 	if err != nil {
@@ -1,8 +1,9 @@
 package mmap
 
 import (
-	"bytes"
+	"errors"
 	"io"
+	"unicode/utf8"
 )
 
 var (
@@ -11,14 +12,16 @@ var (
 )
 
 func (m *Mmap) ReadAt(p []byte, off int64) (n int, err error) {
-	if m.closed {
-		return 0, ErrIsClosed
+	if err = m.rlock(0); err != nil {
+		return 0, err
 	}
+	defer m.mu.RUnlock()
 
-	if off > int64(m.Cap()) {
+	length := int64(m.Len())
+	if off > length {
 		return 0, io.EOF
 	}
-	n = copy(p, m.data[off:])
+	n = copy(p, m.data[off:length])
 	if n < len(p) {
 		err = io.EOF
 	}
@@ -27,34 +30,150 @@ func (m *Mmap) ReadAt(p []byte, off int64) (n int, err error) {
 }
 
 func (m *Mmap) ReaderAt(off int64) Reader {
-	return &mmapReader{m, off}
+	return &mmapReader{m: m, pos: off, prevRune: -1}
 }
 
+// Reader reads the map sequentially from its position up to Len. It works
+// like a bytes.Reader over the map, but keeps up with growth of the map.
 type Reader interface {
-	io.Reader
+	io.ReadSeeker
+	io.ByteScanner
+	io.RuneScanner
+	io.WriterTo
+
+	// Pos returns the current position, relative to the start of the map
+	Pos() int64
 }
 
+var (
+	errUnreadByte = errors.New("mmap.Reader.UnreadByte: at beginning of map")
+	errUnreadRune = errors.New("mmap.Reader.UnreadRune: previous operation was not ReadRune")
+	errWhence     = errors.New("mmap.Seek: invalid whence")
+	errNegative   = errors.New("mmap.Seek: negative position")
+)
+
 type mmapReader struct {
-	m   *Mmap
-	pos int64
+	m        *Mmap
+	pos      int64
+	prevRune int64 // position of the last rune read, or -1
 }
 
 func (r *mmapReader) Read(p []byte) (n int, err error) {
+	r.prevRune = -1
 	n, err = r.m.ReadAt(p, r.pos)
 	r.pos += int64(n)
 	return
 }
 
+func (r *mmapReader) ReadByte() (byte, error) {
+	r.prevRune = -1
+
+	var p [1]byte
+	if _, err := r.m.ReadAt(p[:], r.pos); err != nil {
+		return 0, err
+	}
+
+	r.pos++
+	return p[0], nil
+}
+
+func (r *mmapReader) UnreadByte() error {
+	if r.pos <= 0 {
+		return errUnreadByte
+	}
+
+	r.prevRune = -1
+	r.pos--
+	return nil
+}
+
+func (r *mmapReader) ReadRune() (ch rune, size int, err error) {
+	r.prevRune = -1
+
+	var p [utf8.UTFMax]byte
+	n, err := r.m.ReadAt(p[:], r.pos)
+	if n == 0 {
+		return 0, 0, err
+	}
+
+	r.prevRune = r.pos
+	if c := p[0]; c < utf8.RuneSelf {
+		r.pos++
+		return rune(c), 1, nil
+	}
+
+	ch, size = utf8.DecodeRune(p[:n])
+	r.pos += int64(size)
+	return ch, size, nil
+}
+
+func (r *mmapReader) UnreadRune() error {
+	if r.prevRune < 0 {
+		return errUnreadRune
+	}
+
+	r.pos = r.prevRune
+	r.prevRune = -1
+	return nil
+}
+
+func (r *mmapReader) Seek(offset int64, whence int) (int64, error) {
+	r.prevRune = -1
+
+	pos, err := seek(r.m, r.pos, offset, whence)
+	if err != nil {
+		return r.pos, err
+	}
+
+	r.pos = pos
+	return pos, nil
+}
+
+// WriteTo writes everything from the position up to Len to w.
+func (r *mmapReader) WriteTo(w io.Writer) (n int64, err error) {
+	r.prevRune = -1
+	if r.pos >= int64(r.m.Len()) {
+		return 0, nil
+	}
+
+	n, err = r.m.WriteToAt(r.pos, w)
+	r.pos += n
+	return
+}
+
+func (r *mmapReader) Pos() int64 {
+	return r.pos
+}
+
+// seek implements io.Seeker for readers and writers at pos, io.SeekEnd is
+// relative to Len.
+func seek(m *Mmap, pos, offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += pos
+	case io.SeekEnd:
+		offset += int64(m.Len())
+	default:
+		return 0, errWhence
+	}
+
+	if offset < 0 {
+		return 0, errNegative
+	}
+	return offset, nil
+}
+
 func (m *Mmap) WriteTo(w io.Writer) (n int64, err error) {
-	if m.closed {
+	if m.IsClosed() {
 		return 0, ErrIsClosed
 	}
 
-	return io.Copy(w, bytes.NewReader(m.data))
+	return m.writeTo(w, 0, int64(m.Len()))
 }
 
 func (m *Mmap) Bytes(offset int64, length int) ([]byte, error) {
-	if m.closed {
+	if m.IsClosed() {
 		return nil, ErrIsClosed
 	}
 
@@ -65,12 +184,82 @@ func (m *Mmap) Bytes(offset int64, length int) ([]byte, error) {
 }
 
 func (m *Mmap) WriteToAt(offset int64, w io.Writer) (n int64, err error) {
-	if m.closed {
+	if m.IsClosed() {
 		return 0, ErrIsClosed
 	}
-	if offset > int64(m.Cap()) {
+
+	length := int64(m.Len())
+	if offset > length {
 		return 0, io.EOF
 	}
 
-	return io.Copy(w, bytes.NewReader(m.data[offset:]))
+	return m.writeTo(w, offset, length)
+}
+
+// WriteRangeTo writes length bytes from offset on to w. Like ReadAt, it
+// returns io.EOF if the range reaches beyond Len.
+func (m *Mmap) WriteRangeTo(offset int64, length int, w io.Writer) (n int64, err error) {
+	if offset < 0 || length < 0 {
+		return 0, ErrOverflow
+	}
+
+	if m.IsClosed() {
+		return 0, ErrIsClosed
+	}
+
+	end := offset + int64(length)
+	if size := int64(m.Len()); end > size {
+		if offset > size {
+			return 0, io.EOF
+		}
+		n, err = m.writeTo(w, offset, size)
+		if err == nil {
+			err = io.EOF
+		}
+		return n, err
+	}
+
+	return m.writeTo(w, offset, end)
+}
+
+// writeTo writes data[start:end] to w, with sendfile from the backing file
+// where possible. It copies in chunks and holds mu only while it reads one, so
+// a slow w holds up nobody and w may as well write to the map itself. A map
+// that shrinks meanwhile ends it with io.EOF.
+func (m *Mmap) writeTo(w io.Writer, start, end int64) (n int64, err error) {
+	n, handled, err := m.sendTo(w, start, end)
+	if handled {
+		return n, err
+	}
+
+	size := end - start - n
+	if size > copyChunk {
+		size = copyChunk
+	}
+	buf := make([]byte, size)
+
+	for pos := start + n; pos < end; {
+		chunk := buf
+		if remain := end - pos; remain < int64(len(chunk)) {
+			chunk = chunk[:remain]
+		}
+
+		nr, er := m.ReadAt(chunk, pos)
+		if nr > 0 {
+			nw, ew := w.Write(chunk[:nr])
+			n += int64(nw)
+			pos += int64(nw)
+			if ew != nil {
+				return n, ew
+			}
+			if nw < nr {
+				return n, io.ErrShortWrite
+			}
+		}
+		if er != nil {
+			return n, er
+		}
+	}
+
+	return n, nil
 }
@@ -4,8 +4,12 @@ import (
 	"bytes"
 	"io"
 	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ImSingee/tt"
 )
@@ -216,12 +220,321 @@ func TestReader(t *testing.T) {
 			tt.AssertEqual(t, "NOPQRSTUVWXYZ", string(buf))
 		}
 
+		// nothing beyond Len, although the capacity is larger
 		remain, err := ioutil.ReadAll(r)
 		tt.AssertIsNotError(t, err)
-		tt.AssertEqual(t, make([]byte, mmap.Cap()-len(data)), remain)
+		tt.AssertEqual(t, 0, len(remain))
 
 		n, err := r.Read(buf)
 		tt.AssertEqual(t, io.EOF, err)
 		tt.AssertEqual(t, 0, n)
 	})
 }
+
+func TestReaderScan(t *testing.T) {
+	mmap, err := New(NewReadWrite(""))
+	tt.AssertIsNotError(t, err)
+	defer closeMmap(t, mmap)
+
+	_, err = mmap.WriteAt([]byte("aé世"), 0)
+	tt.AssertIsNotError(t, err)
+
+	r := mmap.ReaderAt(0)
+	tt.AssertEqual(t, errUnreadByte, r.UnreadByte())
+	tt.AssertEqual(t, errUnreadRune, r.UnreadRune())
+
+	c, err := r.ReadByte()
+	tt.AssertIsNotError(t, err)
+	tt.AssertEqual(t, byte('a'), c)
+	tt.AssertIsNotError(t, r.UnreadByte())
+	tt.AssertEqual(t, int64(0), r.Pos())
+
+	for _, expect := range []struct {
+		ch   rune
+		size int
+	}{{'a', 1}, {'é', 2}, {'世', 3}} {
+		ch, size, err := r.ReadRune()
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, expect.ch, ch)
+		tt.AssertEqual(t, expect.size, size)
+	}
+	tt.AssertEqual(t, int64(6), r.Pos())
+
+	tt.AssertIsNotError(t, r.UnreadRune())
+	tt.AssertEqual(t, int64(3), r.Pos())
+	tt.AssertEqual(t, errUnreadRune, r.UnreadRune())
+
+	ch, _, err := r.ReadRune()
+	tt.AssertIsNotError(t, err)
+	tt.AssertEqual(t, '世', ch)
+
+	_, _, err = r.ReadRune()
+	tt.AssertEqual(t, io.EOF, err)
+	_, err = r.ReadByte()
+	tt.AssertEqual(t, io.EOF, err)
+}
+
+func TestReaderSeek(t *testing.T) {
+	f, err := newHelloWorldFile()
+	tt.AssertIsNotError(t, err)
+
+	mmap, err := New(NewReadWrite(f))
+	tt.AssertIsNotError(t, err)
+	defer closeMmap(t, mmap)
+
+	r := mmap.ReaderAt(0)
+
+	pos, err := r.Seek(-6, io.SeekEnd)
+	tt.AssertIsNotError(t, err)
+	tt.AssertEqual(t, int64(6), pos)
+
+	pos, err = r.Seek(1, io.SeekCurrent)
+	tt.AssertIsNotError(t, err)
+	tt.AssertEqual(t, int64(7), pos)
+
+	all, err := ioutil.ReadAll(r)
+	tt.AssertIsNotError(t, err)
+	tt.AssertEqual(t, "orld!", string(all))
+
+	_, err = r.Seek(-1, io.SeekStart)
+	tt.AssertEqual(t, errNegative, err)
+	_, err = r.Seek(0, 42)
+	tt.AssertEqual(t, errWhence, err)
+	tt.AssertEqual(t, int64(LenOfHelloWorld), r.Pos())
+
+	// SeekEnd follows the growth of the map
+	_, err = mmap.WriteAt([]byte("?"), int64(LenOfHelloWorld))
+	tt.AssertIsNotError(t, err)
+	pos, err = r.Seek(-1, io.SeekEnd)
+	tt.AssertIsNotError(t, err)
+	tt.AssertEqual(t, int64(LenOfHelloWorld), pos)
+
+	c, err := r.ReadByte()
+	tt.AssertIsNotError(t, err)
+	tt.AssertEqual(t, byte('?'), c)
+}
+
+func TestReaderWriteTo(t *testing.T) {
+	f, err := newHelloWorldFile()
+	tt.AssertIsNotError(t, err)
+
+	mmap, err := New(NewReadOnly(f))
+	tt.AssertIsNotError(t, err)
+
+	r := mmap.ReaderAt(6)
+
+	buf := &bytes.Buffer{}
+	n, err := r.WriteTo(buf)
+	tt.AssertIsNotError(t, err)
+	tt.AssertEqual(t, int64(LenOfHelloWorld-6), n)
+	tt.AssertEqual(t, HelloWorld[6:], buf.String())
+	tt.AssertEqual(t, int64(LenOfHelloWorld), r.Pos())
+
+	// nothing left to write is no error
+	n, err = r.WriteTo(buf)
+	tt.AssertIsNotError(t, err)
+	tt.AssertEqual(t, int64(0), n)
+
+	closeMmap(t, mmap)
+
+	_, err = r.Seek(0, io.SeekStart)
+	tt.AssertIsNotError(t, err)
+	_, err = r.ReadByte()
+	tt.AssertEqual(t, ErrIsClosed, err)
+	_, _, err = r.ReadRune()
+	tt.AssertEqual(t, ErrIsClosed, err)
+	_, err = r.WriteTo(buf)
+	tt.AssertEqual(t, ErrIsClosed, err)
+}
+
+func TestReaderWriteToSelf(t *testing.T) {
+	f, err := newHelloWorldFile()
+	tt.AssertIsNotError(t, err)
+
+	mmap, err := New(NewReadWrite(f))
+	tt.AssertIsNotError(t, err)
+	defer closeMmap(t, mmap)
+
+	// the writer grows the map the reader is writing from
+	within(t, func() error {
+		_, err := io.Copy(mmap.WriterAt(int64(mmap.Len())), mmap.ReaderAt(0))
+		return err
+	})
+	tt.AssertEqual(t, HelloWorld+HelloWorld, string(mmap.data[:mmap.Len()]))
+}
+
+func TestWriteRangeTo(t *testing.T) {
+	f, err := newHelloWorldFile()
+	tt.AssertIsNotError(t, err)
+
+	mmap, err := New(NewReadOnly(f))
+	tt.AssertIsNotError(t, err)
+	defer closeMmap(t, mmap)
+
+	buf := &bytes.Buffer{}
+	n, err := mmap.WriteRangeTo(6, 5, buf)
+	tt.AssertIsNotError(t, err)
+	tt.AssertEqual(t, int64(5), n)
+	tt.AssertEqual(t, "world", buf.String())
+
+	buf.Reset()
+	n, err = mmap.WriteRangeTo(6, 128, buf)
+	tt.AssertEqual(t, io.EOF, err)
+	tt.AssertEqual(t, int64(LenOfHelloWorld-6), n)
+	tt.AssertEqual(t, "world!", buf.String())
+
+	buf.Reset()
+	n, err = mmap.WriteRangeTo(666, 1, buf)
+	tt.AssertEqual(t, io.EOF, err)
+	tt.AssertEqual(t, int64(0), n)
+
+	_, err = mmap.WriteRangeTo(-1, 1, buf)
+	tt.AssertEqual(t, ErrOverflow, err)
+	_, err = mmap.WriteRangeTo(0, -1, buf)
+	tt.AssertEqual(t, ErrOverflow, err)
+}
+
+func TestWriteToConn(t *testing.T) {
+	_, pattern := newPatternFile(t, 8*oneMB+5)
+
+	newMmap := func(t *testing.T, private bool) *Mmap {
+		args := NewReadWrite("")
+		args.Private = private
+
+		mmap, err := New(args)
+		tt.AssertIsNotError(t, err)
+		_, err = mmap.WriteAt(pattern, 0)
+		tt.AssertIsNotError(t, err)
+		return mmap
+	}
+
+	// send writes the map to one end of a connected pair and returns what
+	// arrives at the other
+	send := func(t *testing.T, network string, mmap *Mmap, write func(net.Conn) (int64, error)) []byte {
+		addr := "127.0.0.1:0"
+		if network == "unix" {
+			dir, err := ioutil.TempDir("", "")
+			tt.AssertIsNotError(t, err)
+			defer os.RemoveAll(dir)
+
+			addr = filepath.Join(dir, "sock")
+		}
+
+		l, err := net.Listen(network, addr)
+		tt.AssertIsNotError(t, err)
+		defer l.Close()
+
+		received := make(chan []byte, 1)
+		go func() {
+			conn, err := l.Accept()
+			if err != nil {
+				received <- nil
+				return
+			}
+			defer conn.Close()
+
+			p, _ := ioutil.ReadAll(conn)
+			received <- p
+		}()
+
+		conn, err := net.Dial(network, l.Addr().String())
+		tt.AssertIsNotError(t, err)
+
+		_, err = write(conn)
+		tt.AssertIsNotError(t, err)
+		tt.AssertIsNotError(t, conn.Close())
+
+		return <-received
+	}
+
+	for _, network := range []string{"tcp", "unix"} {
+		t.Run(network, func(t *testing.T) {
+			mmap := newMmap(t, false)
+			defer closeMmap(t, mmap)
+
+			p := send(t, network, mmap, func(conn net.Conn) (int64, error) {
+				return mmap.WriteTo(conn)
+			})
+			tt.AssertTrue(t, bytes.Equal(pattern, p))
+
+			p = send(t, network, mmap, func(conn net.Conn) (int64, error) {
+				return mmap.WriteToAt(oneMB, conn)
+			})
+			tt.AssertTrue(t, bytes.Equal(pattern[oneMB:], p))
+
+			p = send(t, network, mmap, func(conn net.Conn) (int64, error) {
+				return mmap.WriteRangeTo(3, 2*oneMB, conn)
+			})
+			tt.AssertTrue(t, bytes.Equal(pattern[3:3+2*oneMB], p))
+		})
+	}
+
+	t.Run("private", func(t *testing.T) {
+		mmap := newMmap(t, true)
+		defer closeMmap(t, mmap)
+
+		p := send(t, "tcp", mmap, func(conn net.Conn) (int64, error) {
+			return mmap.WriteTo(conn)
+		})
+		tt.AssertTrue(t, bytes.Equal(pattern, p))
+	})
+
+	// a peer that does not read holds up neither growth nor access, with
+	// sendfile as well as with copying
+	for name, private := range map[string]bool{"slow-peer": false, "slow-peer-private": true} {
+		private := private
+		t.Run(name, func(t *testing.T) {
+			mmap := newMmap(t, private)
+			defer closeMmap(t, mmap)
+
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			tt.AssertIsNotError(t, err)
+			defer l.Close()
+
+			conn, err := net.Dial("tcp", l.Addr().String())
+			tt.AssertIsNotError(t, err)
+			peer, err := l.Accept()
+			tt.AssertIsNotError(t, err)
+			defer peer.Close()
+
+			sent := make(chan error, 1)
+			go func() {
+				_, err := mmap.WriteTo(conn)
+				_ = conn.Close()
+				sent <- err
+			}()
+
+			time.Sleep(20 * time.Millisecond)
+			within(t, func() error {
+				if err := mmap.EnsureCapacity(16 * oneMB); err != nil {
+					return err
+				}
+				_, err := mmap.WriteAt([]byte(HelloWorld), 12*oneMB)
+				return err
+			})
+
+			p, err := ioutil.ReadAll(peer)
+			tt.AssertIsNotError(t, err)
+			tt.AssertIsNotError(t, <-sent)
+			tt.AssertTrue(t, bytes.Equal(pattern, p))
+		})
+	}
+
+	t.Run("file", func(t *testing.T) {
+		mmap := newMmap(t, false)
+		defer closeMmap(t, mmap)
+
+		dst, err := ioutil.TempFile("", "")
+		tt.AssertIsNotError(t, err)
+		defer os.Remove(dst.Name())
+		defer dst.Close()
+
+		n, err := mmap.WriteToAt(5, dst)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, int64(len(pattern)-5), n)
+
+		p, err := ioutil.ReadFile(dst.Name())
+		tt.AssertIsNotError(t, err)
+		tt.AssertTrue(t, bytes.Equal(pattern[5:], p))
+	})
+}
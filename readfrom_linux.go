@@ -0,0 +1,99 @@
+package mmap
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// readFromFile copies src into the backing file within the kernel, with
+// copy_file_range for a regular file and splice for a pipe. The mapping shares
+// the page cache with the file, so the data shows up in it right away. It
+// reports whether it handled the copy, if not nothing has been read from src.
+func (m *Mmap) readFromFile(off int64, src *os.File) (n int64, handled bool, err error) {
+	if m.anonymous() || m.args.Prot()&unix.PROT_WRITE == 0 || m.args.Flags()&unix.MAP_SHARED == 0 {
+		return 0, false, nil
+	}
+
+	stat, err := src.Stat()
+	if err != nil {
+		return 0, false, nil
+	}
+
+	var copyFd func(rfd, wfd int, woff int64, length int) (int, error)
+	switch mode := stat.Mode(); {
+	case mode.IsRegular():
+		copyFd = copyFileRange
+	case mode&os.ModeNamedPipe != 0:
+		copyFd = splice
+	default:
+		return 0, false, nil
+	}
+
+	rc, err := src.SyscallConn()
+	if err != nil {
+		return 0, false, nil
+	}
+
+	dst, err := m.dupFd()
+	if err != nil {
+		return 0, true, err
+	}
+	defer unix.Close(dst)
+
+	for {
+		pos := int(off + n)
+		if err = m.rlock(pos + copyChunk); err != nil {
+			return n, true, err
+		}
+		room := len(m.data) - pos
+		m.mu.RUnlock()
+
+		// the copy goes to the file rather than the mapping, so it waits for
+		// src without holding the map
+		var nc int
+		woff := m.offset() + int64(pos)
+		re := rc.Read(func(fd uintptr) bool {
+			if nc, err = copyFd(int(fd), dst, woff, room); nc < 0 {
+				nc = 0
+			}
+			return err != unix.EAGAIN
+		})
+		if nc > 0 {
+			m.extend(pos + nc)
+		}
+
+		if err == nil {
+			err = re
+		}
+		if err != nil {
+			if n == 0 && canFallBack(err) {
+				return 0, false, nil
+			}
+			return n, true, err
+		}
+		if nc == 0 {
+			return n, true, nil
+		}
+		n += int64(nc)
+	}
+}
+
+func copyFileRange(rfd, wfd int, woff int64, length int) (int, error) {
+	return unix.CopyFileRange(rfd, nil, wfd, &woff, length, 0)
+}
+
+func splice(rfd, wfd int, woff int64, length int) (int, error) {
+	n, err := unix.Splice(rfd, nil, wfd, &woff, length, unix.SPLICE_F_MOVE)
+	return int(n), err
+}
+
+// canFallBack reports whether err means the kernel cannot copy between the
+// two files, e.g. across file systems before Linux 5.3
+func canFallBack(err error) bool {
+	switch err {
+	case unix.ENOSYS, unix.EXDEV, unix.EINVAL, unix.EOPNOTSUPP, unix.EBADF, unix.EPERM:
+		return true
+	}
+	return false
+}
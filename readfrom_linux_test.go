@@ -0,0 +1,136 @@
+package mmap
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ImSingee/tt"
+)
+
+func TestReadFromFile(t *testing.T) {
+	t.Run("regular", func(t *testing.T) {
+		name, pattern := newPatternFile(t, 5*oneMB+123)
+
+		src, err := os.Open(name)
+		tt.AssertIsNotError(t, err)
+		defer src.Close()
+
+		_, err = src.Seek(100, io.SeekStart)
+		tt.AssertIsNotError(t, err)
+
+		mmap, err := New(NewReadWrite(""))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		n, err := mmap.ReadFromAt(10, src)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, int64(len(pattern)-100), n)
+		tt.AssertEqual(t, 10+len(pattern)-100, mmap.Len())
+		tt.AssertTrue(t, bytes.Equal(pattern[100:], mmap.data[10:mmap.Len()]))
+
+		// the offset of src moved as with Read
+		pos, err := src.Seek(0, io.SeekCurrent)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, int64(len(pattern)), pos)
+	})
+
+	t.Run("pipe", func(t *testing.T) {
+		_, pattern := newPatternFile(t, 3*oneMB+7)
+
+		pr, pw, err := os.Pipe()
+		tt.AssertIsNotError(t, err)
+		defer pr.Close()
+
+		go func() {
+			defer pw.Close()
+			_, _ = pw.Write(pattern)
+		}()
+
+		f, err := newHelloWorldFile()
+		tt.AssertIsNotError(t, err)
+
+		args := NewReadWrite(f)
+		args.FileOffset = 6
+		args.TrimOnClose = true
+
+		mmap, err := New(args)
+		tt.AssertIsNotError(t, err)
+
+		n, err := mmap.ReadFromAt(2, pr)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, int64(len(pattern)), n)
+		tt.AssertEqual(t, 2+len(pattern), mmap.Len())
+		tt.AssertTrue(t, bytes.Equal(pattern, mmap.data[2:mmap.Len()]))
+		closeMmap(t, mmap)
+
+		p, err := ioutil.ReadFile(f)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, 8+len(pattern), len(p))
+		tt.AssertEqual(t, "Hello wo", string(p[:8]))
+		tt.AssertTrue(t, bytes.Equal(pattern, p[8:]))
+	})
+
+	t.Run("private", func(t *testing.T) {
+		name, pattern := newPatternFile(t, 2*oneMB)
+
+		src, err := os.Open(name)
+		tt.AssertIsNotError(t, err)
+		defer src.Close()
+
+		f, err := newHelloWorldFile()
+		tt.AssertIsNotError(t, err)
+
+		args := NewReadWrite(f)
+		args.Private = true
+
+		mmap, err := New(args)
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		n, err := mmap.ReadFrom(src)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, int64(len(pattern)), n)
+		tt.AssertTrue(t, bytes.Equal(pattern, mmap.data[:mmap.Len()]))
+
+		// the copy never reached the file
+		p, err := ioutil.ReadFile(f)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, HelloWorld, string(p[:LenOfHelloWorld]))
+	})
+
+	t.Run("idle-pipe", func(t *testing.T) {
+		pr, pw, err := os.Pipe()
+		tt.AssertIsNotError(t, err)
+		defer pr.Close()
+
+		mmap, err := New(NewReadWrite(""))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		read := make(chan error, 1)
+		go func() {
+			_, err := mmap.ReadFrom(pr)
+			read <- err
+		}()
+
+		// splice waits for the pipe without holding the map
+		time.Sleep(20 * time.Millisecond)
+		within(t, func() error {
+			if err := mmap.EnsureCapacity(4 * oneMB); err != nil {
+				return err
+			}
+			_, err := mmap.WriteAt([]byte(HelloWorld), 3*oneMB)
+			return err
+		})
+
+		_, err = pw.WriteString(HelloWorld)
+		tt.AssertIsNotError(t, err)
+		tt.AssertIsNotError(t, pw.Close())
+		tt.AssertIsNotError(t, <-read)
+		tt.AssertEqual(t, HelloWorld, string(mmap.data[:LenOfHelloWorld]))
+	})
+}
@@ -0,0 +1,194 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package mmap
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+)
+
+// ReloadingMmap maps a file read-only and maps it again whenever a new version
+// replaces it, be it renamed over the path or written in place. Readers
+// Acquire the current version and keep it mapped until they Release it, no
+// matter how many versions replaced it meanwhile.
+//
+// New versions are noticed with inotify on the directory where available and
+// by polling otherwise. Only a changed inode, size or modification time counts
+// as a new version. A file rewritten in place still changes under the
+// versions already mapped, and shrinking it makes them fault, so new versions
+// should rather be renamed over the path.
+type ReloadingMmap struct {
+	file string
+
+	mu      sync.RWMutex
+	current *Snapshot // nil once closed
+	stat    os.FileInfo
+	err     error
+
+	reload sync.Mutex // serializes reloads
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Snapshot is a version of a ReloadingMmap. It must be released rather than
+// closed, the last release unmaps it.
+type Snapshot struct {
+	*Mmap
+
+	refs int32 // readers, plus one while it is the current version
+}
+
+// Release gives the snapshot back, it must not be used afterwards.
+func (s *Snapshot) Release() error {
+	if atomic.AddInt32(&s.refs, -1) == 0 {
+		return s.Mmap.Close()
+	}
+	return nil
+}
+
+// NewReloadingMmap maps file and starts watching it for new versions.
+func NewReloadingMmap(file string) (*ReloadingMmap, error) {
+	r := &ReloadingMmap{file: file, done: make(chan struct{})}
+
+	snapshot, stat, err := r.open()
+	if err != nil {
+		return nil, err
+	}
+	r.current, r.stat = snapshot, stat
+
+	// rename replaces the inode, so the watch is on the directory
+	w, err := watchDir(filepath.Dir(file))
+	if err != nil && err != ErrNotSupported {
+		_ = snapshot.Release()
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	go r.watch(ctx, w)
+
+	return r, nil
+}
+
+// open maps the file as it is now, along with the stat of what it mapped
+func (r *ReloadingMmap) open() (*Snapshot, os.FileInfo, error) {
+	m, err := New(NewReadOnly(r.file))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stat, err := m.File().Stat()
+	if err != nil {
+		_ = m.Close()
+		return nil, nil, err
+	}
+
+	return &Snapshot{Mmap: m, refs: 1}, stat, nil
+}
+
+func (r *ReloadingMmap) watch(ctx context.Context, w *watcher) {
+	defer close(r.done)
+	if w != nil {
+		defer w.close()
+	}
+
+	for {
+		err := waitChange(ctx, w)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			err = r.Reload()
+		}
+
+		r.mu.Lock()
+		r.err = err
+		r.mu.Unlock()
+	}
+}
+
+// Acquire returns the current version, which stays mapped until it is
+// released.
+func (r *ReloadingMmap) Acquire() (*Snapshot, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.current == nil {
+		return nil, ErrIsClosed
+	}
+
+	atomic.AddInt32(&r.current.refs, 1)
+	return r.current, nil
+}
+
+// Reload maps the file again if it changed since the current version was
+// mapped. It happens by itself, but callers that just replaced the file need
+// not wait for that.
+func (r *ReloadingMmap) Reload() error {
+	r.reload.Lock()
+	defer r.reload.Unlock()
+
+	r.mu.RLock()
+	closed, last := r.current == nil, r.stat
+	r.mu.RUnlock()
+	if closed {
+		return ErrIsClosed
+	}
+
+	stat, err := os.Stat(r.file)
+	if err != nil {
+		return err
+	}
+	if os.SameFile(stat, last) && stat.Size() == last.Size() && stat.ModTime().Equal(last.ModTime()) {
+		return nil
+	}
+
+	snapshot, stat, err := r.open()
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	old := r.current
+	if old == nil {
+		r.mu.Unlock()
+		return snapshot.Release()
+	}
+	r.current, r.stat = snapshot, stat
+	r.mu.Unlock()
+
+	return old.Release()
+}
+
+// Err returns the error of the last reload in the background, it is nil again
+// once a reload succeeds.
+func (r *ReloadingMmap) Err() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.err
+}
+
+// Close stops watching the file and releases the current version, versions
+// still acquired stay mapped until they are released.
+func (r *ReloadingMmap) Close() error {
+	r.cancel()
+	<-r.done
+
+	r.reload.Lock()
+	defer r.reload.Unlock()
+
+	r.mu.Lock()
+	current := r.current
+	r.current = nil
+	r.mu.Unlock()
+
+	if current == nil {
+		return nil
+	}
+	return current.Release()
+}
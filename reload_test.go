@@ -0,0 +1,133 @@
+package mmap
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ImSingee/tt"
+)
+
+// nextSnapshot waits for r to map a version other than old
+func nextSnapshot(t *testing.T, r *ReloadingMmap, old *Snapshot) *Snapshot {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		s, err := r.Acquire()
+		tt.AssertIsNotError(t, err)
+		if s != old {
+			return s
+		}
+		tt.AssertIsNotError(t, s.Release())
+	}
+
+	t.Fatal("no new version was mapped")
+	return nil
+}
+
+func snapshotString(t *testing.T, s *Snapshot) string {
+	t.Helper()
+
+	p, err := s.Bytes(0, s.Len())
+	tt.AssertIsNotError(t, err)
+	return string(p)
+}
+
+func TestReloadingMmap(t *testing.T) {
+	t.Run("rename", func(t *testing.T) {
+		f, err := newHelloWorldFile()
+		tt.AssertIsNotError(t, err)
+
+		r, err := NewReloadingMmap(f)
+		tt.AssertIsNotError(t, err)
+		defer func() { tt.AssertIsNotError(t, r.Close()) }()
+
+		old, err := r.Acquire()
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, HelloWorld, snapshotString(t, old))
+
+		tmp := filepath.Join(filepath.Dir(f), "next")
+		tt.AssertIsNotError(t, ioutil.WriteFile(tmp, []byte("Hello Bryan!"), 0644))
+		tt.AssertIsNotError(t, os.Rename(tmp, f))
+
+		s := nextSnapshot(t, r, old)
+		tt.AssertEqual(t, "Hello Bryan!", snapshotString(t, s))
+		tt.AssertIsNotError(t, s.Release())
+
+		// the old version stays mapped until it is released
+		tt.AssertEqual(t, HelloWorld, snapshotString(t, old))
+		tt.AssertIsNotError(t, old.Release())
+		tt.AssertTrue(t, old.IsClosed())
+	})
+
+	t.Run("in-place", func(t *testing.T) {
+		f, err := newHelloWorldFile()
+		tt.AssertIsNotError(t, err)
+
+		r, err := NewReloadingMmap(f)
+		tt.AssertIsNotError(t, err)
+		defer func() { tt.AssertIsNotError(t, r.Close()) }()
+
+		old, err := r.Acquire()
+		tt.AssertIsNotError(t, err)
+		tt.AssertIsNotError(t, old.Release())
+
+		tt.AssertIsNotError(t, ioutil.WriteFile(f, []byte(HelloWorld+HelloWorld), 0644))
+
+		s := nextSnapshot(t, r, old)
+		tt.AssertEqual(t, HelloWorld+HelloWorld, snapshotString(t, s))
+		tt.AssertIsNotError(t, s.Release())
+		tt.AssertIsNotError(t, r.Err())
+	})
+
+	t.Run("reload", func(t *testing.T) {
+		f, err := newHelloWorldFile()
+		tt.AssertIsNotError(t, err)
+
+		r, err := NewReloadingMmap(f)
+		tt.AssertIsNotError(t, err)
+
+		old, err := r.Acquire()
+		tt.AssertIsNotError(t, err)
+
+		// nothing changed, nothing to map
+		tt.AssertIsNotError(t, r.Reload())
+		s, err := r.Acquire()
+		tt.AssertIsNotError(t, err)
+		tt.AssertTrue(t, s == old)
+		tt.AssertIsNotError(t, s.Release())
+
+		tt.AssertIsNotError(t, r.Close())
+		_, err = r.Acquire()
+		tt.AssertEqual(t, ErrIsClosed, err)
+		tt.AssertEqual(t, ErrIsClosed, r.Reload())
+
+		tt.AssertEqual(t, HelloWorld, snapshotString(t, old))
+		tt.AssertIsNotError(t, old.Release())
+		tt.AssertTrue(t, old.IsClosed())
+	})
+
+	t.Run("read-only", func(t *testing.T) {
+		f, err := newHelloWorldFile()
+		tt.AssertIsNotError(t, err)
+
+		r, err := NewReloadingMmap(f)
+		tt.AssertIsNotError(t, err)
+		defer func() { tt.AssertIsNotError(t, r.Close()) }()
+
+		s, err := r.Acquire()
+		tt.AssertIsNotError(t, err)
+		defer func() { tt.AssertIsNotError(t, s.Release()) }()
+
+		_, err = s.WriteAt([]byte(HelloWorld), 0)
+		tt.AssertEqual(t, ErrReadOnly, err)
+		tt.AssertEqual(t, HelloWorld, snapshotString(t, s))
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		_, err := NewReloadingMmap(filepath.Join(os.TempDir(), "does-not-exist"))
+		tt.AssertTrue(t, os.IsNotExist(err))
+	})
+}
@@ -0,0 +1,98 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package mmap
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+type shouldReserve interface {
+	ReservedSize() int
+}
+
+func (m *Mmap) reservation() int {
+	if args, ok := m.args.(shouldReserve); ok && args.ReservedSize() > 0 {
+		return alignPage(args.ReservedSize())
+	}
+	return 0
+}
+
+// reserve maps an inaccessible region that the file is later mapped into
+// with MAP_FIXED, so the base address of data never changes.
+func (m *Mmap) reserve(size int) (err error) {
+	m.reserved, err = sysMmap(0, size, unix.PROT_NONE, unix.MAP_PRIVATE|unix.MAP_ANON|unix.MAP_NORESERVE, -1, 0)
+	return
+}
+
+// commit maps data[from:to] over the reservation. Pages already committed
+// are left alone, so private copies survive growth as well.
+func (m *Mmap) commit(from, to int) error {
+	from, to = m.committed(from), m.slack+to
+	if from >= to {
+		return nil
+	}
+
+	_, err := sysMmap(addrOf(m.reserved)+uintptr(from), to-from, m.args.Prot(), m.args.Flags()|unix.MAP_FIXED, fdOf(m.file), m.fileOffset()+int64(from))
+	return err
+}
+
+func (m *Mmap) openReserved(withCap int) error {
+	if size := m.reservation(); withCap > size {
+		return ErrReservationExceeded
+	} else if err := m.reserve(alignPage(m.slack + size)); err != nil {
+		return err
+	}
+
+	if err := m.commit(0, withCap); err != nil {
+		_ = sysMunmap(m.reserved)
+		m.reserved = nil
+		return err
+	}
+
+	m.setData(m.reserved, withCap)
+	m.closed = false
+	return nil
+}
+
+// committed returns where the pages committed for data[:size] end within
+// the reservation
+func (m *Mmap) committed(size int) int {
+	if size == 0 {
+		return 0
+	}
+	return alignPage(m.slack + size)
+}
+
+// decommit gives the pages of data[from:to] back to the reservation
+func (m *Mmap) decommit(from, to int) error {
+	from, to = m.committed(from), alignPage(m.slack+to)
+	if from >= to {
+		return nil
+	}
+
+	_, err := sysMmap(addrOf(m.reserved)+uintptr(from), to-from, unix.PROT_NONE, unix.MAP_PRIVATE|unix.MAP_ANON|unix.MAP_NORESERVE|unix.MAP_FIXED, -1, 0)
+	return err
+}
+
+func (m *Mmap) remapReserved(newCap int) error {
+	if newCap < len(m.data) {
+		if err := m.decommit(newCap, len(m.data)); err != nil {
+			return err
+		}
+
+		m.setData(m.reserved, newCap)
+		return nil
+	}
+
+	if _, err := m.extendFile(newCap); err != nil {
+		return err
+	}
+
+	if err := m.commit(len(m.data), newCap); err != nil {
+		return err
+	}
+
+	m.setData(m.reserved, newCap)
+	return nil
+}
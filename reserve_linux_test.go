@@ -0,0 +1,164 @@
+package mmap
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/ImSingee/tt"
+)
+
+func TestReserve(t *testing.T) {
+	t.Run("base-never-moves", func(t *testing.T) {
+		args := NewReadWrite("")
+		args.ReserveSize = oneGB
+
+		mmap, err := New(args)
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		_, err = mmap.WriteAt([]byte(HelloWorld), 0)
+		tt.AssertIsNotError(t, err)
+
+		base := &mmap.data[0]
+		for i := 1; i <= 16; i++ {
+			_, err = mmap.WriteAt([]byte{byte(i)}, int64(i*oneMB))
+			tt.AssertIsNotError(t, err)
+			tt.AssertTrue(t, base == &mmap.data[0])
+		}
+
+		tt.AssertEqual(t, 32*oneMB, mmap.Cap())
+		tt.AssertEqual(t, HelloWorld, string(mmap.data[:LenOfHelloWorld]))
+		for i := 1; i <= 16; i++ {
+			tt.AssertEqual(t, byte(i), mmap.data[i*oneMB])
+		}
+
+		tt.AssertIsNotError(t, mmap.Flush())
+		p, err := ioutil.ReadFile(args.File)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, HelloWorld, string(p[:LenOfHelloWorld]))
+		tt.AssertEqual(t, byte(16), p[16*oneMB])
+	})
+
+	t.Run("grow-from-unaligned", func(t *testing.T) {
+		f, err := newHelloWorldFile()
+		tt.AssertIsNotError(t, err)
+
+		args := NewReadWrite(f)
+		args.ReserveSize = 8 * oneMB
+
+		mmap, err := New(args)
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		tt.AssertEqual(t, LenOfHelloWorld, mmap.Cap())
+
+		_, err = mmap.WriteAt([]byte("Bryan"), 6)
+		tt.AssertIsNotError(t, err)
+		_, err = mmap.WriteAt([]byte{1}, int64(LenOfHelloWorld))
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, oneMB, mmap.Cap())
+
+		tt.AssertEqual(t, "Hello Bryan!", string(mmap.data[:LenOfHelloWorld]))
+	})
+
+	t.Run("clamp-to-reservation", func(t *testing.T) {
+		args := NewReadWrite("")
+		args.ReserveSize = 3 * oneMB
+
+		mmap, err := New(args)
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		tt.AssertIsNotError(t, mmap.EnsureCapacity(2*oneMB))
+		tt.AssertEqual(t, 2*oneMB, mmap.Cap())
+
+		// DefaultGrowPolicy wants 4 MB, but 3 MB is enough
+		_, err = mmap.WriteAt([]byte{1}, 2*oneMB)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, 3*oneMB, mmap.Cap())
+
+		n, err := mmap.WriteAt([]byte{1}, 3*oneMB)
+		tt.AssertEqual(t, ErrReservationExceeded, err)
+		tt.AssertEqual(t, 0, n)
+		tt.AssertEqual(t, 3*oneMB, mmap.Cap())
+		tt.AssertFalse(t, mmap.IsClosed())
+	})
+
+	t.Run("initial-exceeds-reservation", func(t *testing.T) {
+		args := NewReadWrite("")
+		args.ReserveSize = 4096
+
+		mmap, err := New(args)
+		tt.AssertEqual(t, ErrReservationExceeded, err)
+		tt.AssertTrue(t, mmap.IsClosed())
+	})
+
+	t.Run("empty-file", func(t *testing.T) {
+		f, err := ioutil.TempFile("", "")
+		tt.AssertIsNotError(t, err)
+		_ = f.Close()
+
+		args := NewReadWrite(f.Name())
+		args.ReserveSize = oneGB
+
+		mmap, err := New(args)
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		tt.AssertEqual(t, 0, mmap.Cap())
+
+		_, err = mmap.WriteAt([]byte(HelloWorld), 0)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, oneMB, mmap.Cap())
+	})
+
+	t.Run("window", func(t *testing.T) {
+		f, err := newHelloWorldFile()
+		tt.AssertIsNotError(t, err)
+
+		args := NewReadWrite(f)
+		args.FileOffset = 6
+		args.InitLength = 6
+		args.ReserveSize = 8 * oneMB
+
+		mmap, err := New(args)
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		base := &mmap.data[0]
+		tt.AssertEqual(t, "world!", string(mmap.data))
+
+		_, err = mmap.WriteAt([]byte(HelloWorld), 3*oneMB)
+		tt.AssertIsNotError(t, err)
+		tt.AssertTrue(t, base == &mmap.data[0])
+		tt.AssertEqual(t, "world!", string(mmap.data[:6]))
+		tt.AssertEqual(t, HelloWorld, string(mmap.data[3*oneMB:3*oneMB+LenOfHelloWorld]))
+
+		tt.AssertIsNotError(t, mmap.Truncate(8*oneMB))
+		tt.AssertEqual(t, ErrReservationExceeded, mmap.Truncate(8*oneMB+1))
+	})
+
+	t.Run("private", func(t *testing.T) {
+		f, err := newHelloWorldFile()
+		tt.AssertIsNotError(t, err)
+
+		args := NewReadWrite(f)
+		args.Private = true
+		args.ReserveSize = 8 * oneMB
+
+		mmap, err := New(args)
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		_, err = mmap.WriteAt([]byte("Bryan"), 6)
+		tt.AssertIsNotError(t, err)
+		_, err = mmap.WriteAt([]byte{1}, 2*oneMB)
+		tt.AssertIsNotError(t, err)
+
+		tt.AssertEqual(t, "Hello Bryan!", string(mmap.data[:LenOfHelloWorld]))
+
+		p, err := ioutil.ReadFile(f)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, HelloWorld, string(p[:LenOfHelloWorld]))
+	})
+}
@@ -0,0 +1,270 @@
+package mmap
+
+import (
+	"encoding/binary"
+	"io"
+	"sync/atomic"
+	"unsafe"
+)
+
+// RingBuffer is a single producer, single consumer queue of messages laid
+// over the map, so that the producer and the consumer can live in different
+// processes that map the same file. Every message is stored with a 4 byte
+// length in front and may wrap around the end of the data area.
+//
+// Only one process may write and only one may read at a time, the ring takes
+// no locks. Waiting works like WaitUint32.
+type RingBuffer struct {
+	m        *Mmap
+	off      int64
+	capacity uint64
+}
+
+// ringHeader is the layout of the first ringHeaderSize bytes of the ring, the
+// data area follows it.
+type ringHeader struct {
+	capacity    uint64
+	head        uint64 // moved by the consumer only
+	tail        uint64 // moved by the producer only
+	overruns    uint64 // messages TryWrite dropped
+	readSeq     uint32 // bumped by every read, the producer waits on it
+	writeSeq    uint32 // bumped by every write, the consumer waits on it
+	readerWaits uint32
+	writerWaits uint32
+}
+
+const ringHeaderSize = 64
+
+const (
+	ringOverruns    = 24
+	ringReadSeq     = 32
+	ringWriteSeq    = 36
+	ringReaderWaits = 40
+	ringWriterWaits = 44
+)
+
+// ringPrefix is the size of the length in front of every message
+const ringPrefix = 4
+
+// RingBuffer lays a ring with capacity bytes of data over the map at off,
+// which must be 8 byte aligned. A zero header is initialized, otherwise its
+// capacity must match, so the other side may pass capacity 0 to take it from
+// the header.
+func (m *Mmap) RingBuffer(off int64, capacity int) (*RingBuffer, error) {
+	if capacity < 0 {
+		return nil, ErrOverflow
+	}
+	if capacity > 0 {
+		if err := m.EnsureCapacity(int(off) + ringHeaderSize + capacity); err != nil {
+			return nil, err
+		}
+	}
+
+	stored, err := m.AtomicLoadUint64(off)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case stored == 0 && capacity == 0:
+		return nil, ErrBadHeader
+	case stored == 0:
+		if _, err := m.CompareAndSwapUint64(off, 0, uint64(capacity)); err != nil {
+			return nil, err
+		}
+		if stored, err = m.AtomicLoadUint64(off); err != nil {
+			return nil, err
+		}
+	}
+	if capacity != 0 && stored != uint64(capacity) {
+		return nil, ErrBadHeader
+	}
+
+	end := int(off) + ringHeaderSize + int(stored)
+	if err := m.rlock(end); err != nil {
+		return nil, err
+	}
+	m.extend(end)
+	m.mu.RUnlock()
+
+	return &RingBuffer{m: m, off: off, capacity: stored}, nil
+}
+
+// Cap returns the size of the data area.
+func (r *RingBuffer) Cap() int {
+	return int(r.capacity)
+}
+
+// Overruns returns the number of messages TryWrite dropped because the ring
+// was full.
+func (r *RingBuffer) Overruns() (uint64, error) {
+	return r.m.AtomicLoadUint64(r.off + ringOverruns)
+}
+
+// Write writes p as a single message, waiting for the consumer to make room.
+func (r *RingBuffer) Write(p []byte) (n int, err error) {
+	for {
+		written, seq, err := r.tryWrite(p)
+		if err != nil {
+			return 0, err
+		}
+		if written {
+			return len(p), nil
+		}
+
+		if err := r.wait(ringWriterWaits, ringReadSeq, seq); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// TryWrite writes p as a single message if there is room, otherwise it counts
+// an overrun and returns ErrFull.
+func (r *RingBuffer) TryWrite(p []byte) error {
+	written, _, err := r.tryWrite(p)
+	if err != nil || written {
+		return err
+	}
+
+	if _, err := r.m.AtomicAddUint64(r.off+ringOverruns, 1); err != nil {
+		return err
+	}
+	return ErrFull
+}
+
+// Read reads the next message into p, waiting for the producer if the ring is
+// empty. A message larger than p stays in the ring and io.ErrShortBuffer is
+// returned.
+func (r *RingBuffer) Read(p []byte) (n int, err error) {
+	for {
+		n, read, seq, err := r.tryRead(p)
+		if err != nil || read {
+			return n, err
+		}
+
+		if err := r.wait(ringReaderWaits, ringWriteSeq, seq); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// TryRead reads the next message into p, or returns ErrEmpty.
+func (r *RingBuffer) TryRead(p []byte) (n int, err error) {
+	n, read, _, err := r.tryRead(p)
+	if err != nil || read {
+		return n, err
+	}
+	return 0, ErrEmpty
+}
+
+// header read-locks the map, growing it to hold the whole ring, and returns
+// the header and the data area of the ring, both reading and writing store to
+// it. The caller must m.mu.RUnlock on success.
+func (r *RingBuffer) header() (*ringHeader, []byte, error) {
+	if r.m.readOnly() {
+		return nil, nil, ErrReadOnly
+	}
+
+	start := r.off + ringHeaderSize
+	if err := r.m.rlock(int(start) + int(r.capacity)); err != nil {
+		return nil, nil, err
+	}
+
+	h := (*ringHeader)(unsafe.Pointer(&r.m.data[r.off]))
+	return h, r.m.data[start : start+int64(r.capacity)], nil
+}
+
+// tryWrite writes p if there is room. Otherwise it returns the read sequence
+// the producer has to wait on.
+func (r *RingBuffer) tryWrite(p []byte) (written bool, seq uint32, err error) {
+	size := uint64(ringPrefix + len(p))
+	if size > r.capacity {
+		return false, 0, ErrOverflow
+	}
+
+	h, data, err := r.header()
+	if err != nil {
+		return false, 0, err
+	}
+	defer r.m.mu.RUnlock()
+
+	seq = atomic.LoadUint32(&h.readSeq)
+	tail := atomic.LoadUint64(&h.tail)
+	if tail+size-atomic.LoadUint64(&h.head) > r.capacity {
+		return false, seq, nil
+	}
+
+	var prefix [ringPrefix]byte
+	binary.LittleEndian.PutUint32(prefix[:], uint32(len(p)))
+	r.copyIn(data, tail, prefix[:])
+	r.copyIn(data, tail+ringPrefix, p)
+
+	atomic.StoreUint64(&h.tail, tail+size)
+	atomic.AddUint32(&h.writeSeq, 1)
+	if atomic.LoadUint32(&h.readerWaits) > 0 {
+		if _, err := futexWake(&h.writeSeq, wakeAll); err != nil {
+			return true, 0, err
+		}
+	}
+	return true, 0, nil
+}
+
+// tryRead reads the next message if there is one. Otherwise it returns the
+// write sequence the consumer has to wait on.
+func (r *RingBuffer) tryRead(p []byte) (n int, read bool, seq uint32, err error) {
+	h, data, err := r.header()
+	if err != nil {
+		return 0, false, 0, err
+	}
+	defer r.m.mu.RUnlock()
+
+	seq = atomic.LoadUint32(&h.writeSeq)
+	head := atomic.LoadUint64(&h.head)
+	if atomic.LoadUint64(&h.tail) == head {
+		return 0, false, seq, nil
+	}
+
+	var prefix [ringPrefix]byte
+	r.copyOut(prefix[:], data, head)
+	n = int(binary.LittleEndian.Uint32(prefix[:]))
+	if n > len(p) {
+		return 0, false, 0, io.ErrShortBuffer
+	}
+	r.copyOut(p[:n], data, head+ringPrefix)
+
+	atomic.StoreUint64(&h.head, head+uint64(ringPrefix+n))
+	atomic.AddUint32(&h.readSeq, 1)
+	if atomic.LoadUint32(&h.writerWaits) > 0 {
+		if _, err := futexWake(&h.readSeq, wakeAll); err != nil {
+			return n, true, 0, err
+		}
+	}
+	return n, true, 0, nil
+}
+
+// wait announces a waiter in the word at waits and waits for the sequence at
+// seqOff to move on from seq.
+func (r *RingBuffer) wait(waits, seqOff int64, seq uint32) error {
+	if _, err := r.m.AtomicAddUint32(r.off+waits, 1); err != nil {
+		return err
+	}
+
+	_, err := r.m.WaitUint32(r.off+seqOff, seq, -1)
+	if _, derr := r.m.AtomicAddUint32(r.off+waits, ^uint32(0)); err == nil {
+		err = derr
+	}
+	return err
+}
+
+// copyIn copies p to the ring position pos, wrapping around the end of data
+func (r *RingBuffer) copyIn(data []byte, pos uint64, p []byte) {
+	n := copy(data[pos%r.capacity:], p)
+	copy(data, p[n:])
+}
+
+// copyOut copies from the ring position pos to p, wrapping around the end of
+// data
+func (r *RingBuffer) copyOut(p []byte, data []byte, pos uint64) {
+	n := copy(p, data[pos%r.capacity:])
+	copy(p[n:], data)
+}
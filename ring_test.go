@@ -0,0 +1,182 @@
+package mmap
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ImSingee/tt"
+)
+
+// ringMessage is the i-th message, of varying size
+func ringMessage(i int) []byte {
+	return []byte(fmt.Sprintf("%d:%s", i, strings.Repeat("x", i%97)))
+}
+
+func TestRingBuffer(t *testing.T) {
+	t.Run("process", func(t *testing.T) {
+		mmap, file := newIpcMmap(t)
+		defer closeMmap(t, mmap)
+
+		// small enough to wrap around many times
+		ring, err := mmap.RingBuffer(ipcRing, 4096)
+		tt.AssertIsNotError(t, err)
+
+		cmd := runHelper("produce", file)
+		tt.AssertIsNotError(t, cmd.Start())
+
+		p := make([]byte, 256)
+		for i := 0; i < helperMessages; i++ {
+			n, err := ring.Read(p)
+			tt.AssertIsNotError(t, err)
+			tt.AssertEqual(t, string(ringMessage(i)), string(p[:n]))
+		}
+		tt.AssertIsNotError(t, cmd.Wait())
+
+		_, err = ring.TryRead(p)
+		tt.AssertEqual(t, ErrEmpty, err)
+	})
+
+	t.Run("goroutines", func(t *testing.T) {
+		mmap, file := newIpcMmap(t)
+		defer closeMmap(t, mmap)
+
+		other, err := New(NewReadWrite(file))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, other)
+
+		producer, err := mmap.RingBuffer(ipcRing, 1000)
+		tt.AssertIsNotError(t, err)
+		consumer, err := other.RingBuffer(ipcRing, 0)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, 1000, consumer.Cap())
+
+		errs := make(chan error, 1)
+		go func() {
+			for i := 0; i < helperMessages; i++ {
+				if _, err := producer.Write(ringMessage(i)); err != nil {
+					errs <- err
+					return
+				}
+			}
+			errs <- nil
+		}()
+
+		p := make([]byte, 256)
+		for i := 0; i < helperMessages; i++ {
+			n, err := consumer.Read(p)
+			tt.AssertIsNotError(t, err)
+			tt.AssertEqual(t, string(ringMessage(i)), string(p[:n]))
+		}
+		tt.AssertIsNotError(t, <-errs)
+	})
+
+	t.Run("non-blocking", func(t *testing.T) {
+		mmap, _ := newIpcMmap(t)
+		defer closeMmap(t, mmap)
+
+		ring, err := mmap.RingBuffer(ipcRing, 16)
+		tt.AssertIsNotError(t, err)
+
+		p := make([]byte, 16)
+		_, err = ring.TryRead(p)
+		tt.AssertEqual(t, ErrEmpty, err)
+
+		tt.AssertIsNotError(t, ring.TryWrite([]byte("hello")))
+		tt.AssertEqual(t, ErrFull, ring.TryWrite([]byte("world")))
+		tt.AssertEqual(t, ErrFull, ring.TryWrite([]byte("abcd")))
+		tt.AssertIsNotError(t, ring.TryWrite([]byte{}))
+
+		overruns, err := ring.Overruns()
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, uint64(2), overruns)
+
+		// the message stays when p is too small
+		_, err = ring.TryRead(p[:4])
+		tt.AssertEqual(t, io.ErrShortBuffer, err)
+
+		n, err := ring.TryRead(p)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, "hello", string(p[:n]))
+		n, err = ring.TryRead(p)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, 0, n)
+
+		// wraps around the end of the data area
+		tt.AssertIsNotError(t, ring.TryWrite([]byte("wrapped!")))
+		n, err = ring.TryRead(p)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, "wrapped!", string(p[:n]))
+
+		_, err = ring.Write(bytes.Repeat([]byte{1}, 13))
+		tt.AssertEqual(t, ErrOverflow, err)
+	})
+
+	t.Run("header", func(t *testing.T) {
+		mmap, _ := newIpcMmap(t)
+		defer closeMmap(t, mmap)
+
+		_, err := mmap.RingBuffer(ipcRing, 0)
+		tt.AssertEqual(t, ErrBadHeader, err)
+
+		_, err = mmap.RingBuffer(ipcRing, 128)
+		tt.AssertIsNotError(t, err)
+		_, err = mmap.RingBuffer(ipcRing, 256)
+		tt.AssertEqual(t, ErrBadHeader, err)
+
+		_, err = mmap.RingBuffer(ipcRing+4, 128)
+		tt.AssertEqual(t, ErrUnaligned, err)
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		mmap, _ := newIpcMmap(t)
+
+		ring, err := mmap.RingBuffer(ipcRing, 128)
+		tt.AssertIsNotError(t, err)
+		closeMmap(t, mmap)
+
+		_, err = ring.Write([]byte(HelloWorld))
+		tt.AssertEqual(t, ErrIsClosed, err)
+		_, err = ring.Read(make([]byte, 16))
+		tt.AssertEqual(t, ErrIsClosed, err)
+	})
+
+	t.Run("shrunk", func(t *testing.T) {
+		mmap, _ := newIpcMmap(t)
+		defer closeMmap(t, mmap)
+
+		ring, err := mmap.RingBuffer(ipcRing, 8192)
+		tt.AssertIsNotError(t, err)
+
+		// the map grows back to hold the ring rather than cut it off
+		tt.AssertIsNotError(t, mmap.Truncate(4096))
+		tt.AssertIsNotError(t, ring.TryWrite([]byte(HelloWorld)))
+		tt.AssertTrue(t, mmap.Cap() >= ipcRing+ringHeaderSize+8192)
+
+		p := make([]byte, 16)
+		n, err := ring.TryRead(p)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, HelloWorld, string(p[:n]))
+	})
+
+	t.Run("read-only", func(t *testing.T) {
+		mmap, file := newIpcMmap(t)
+		defer closeMmap(t, mmap)
+
+		_, err := mmap.RingBuffer(ipcRing, 64)
+		tt.AssertIsNotError(t, err)
+
+		readOnly, err := New(NewReadOnly(file))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, readOnly)
+
+		ring, err := readOnly.RingBuffer(ipcRing, 0)
+		tt.AssertIsNotError(t, err)
+		_, err = ring.Write([]byte(HelloWorld))
+		tt.AssertEqual(t, ErrReadOnly, err)
+		_, err = ring.TryRead(make([]byte, 16))
+		tt.AssertEqual(t, ErrReadOnly, err)
+	})
+}
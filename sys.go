@@ -0,0 +1,21 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package mmap
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+func addrOf(b []byte) uintptr {
+	return uintptr(unsafe.Pointer(&b[:1][0]))
+}
+
+func bytesAt(addr uintptr, length int) (b []byte) {
+	h := (*reflect.SliceHeader)(unsafe.Pointer(&b))
+	h.Data = addr
+	h.Len = length
+	h.Cap = length
+	return
+}
@@ -0,0 +1,22 @@
+package mmap
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+var canMremap = false
+
+func sysMmap(addr uintptr, length, prot, flags, fd int, offset int64) ([]byte, error) {
+	if addr != 0 {
+		return nil, ErrNotSupported
+	}
+	return unix.Mmap(fd, offset, length, prot, flags)
+}
+
+func sysMunmap(b []byte) error {
+	return unix.Munmap(b)
+}
+
+func sysMremap(b []byte, newLength int) ([]byte, error) {
+	return nil, ErrNotSupported
+}
@@ -0,0 +1,39 @@
+package mmap
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// mappings are created and released with raw syscalls instead of unix.Mmap
+// and unix.Munmap because the latter refuses to unmap a region that has been
+// moved by mremap
+
+const _MREMAP_MAYMOVE = 0x1
+
+var canMremap = true
+
+func sysMmap(addr uintptr, length, prot, flags, fd int, offset int64) ([]byte, error) {
+	r, _, errno := unix.Syscall6(unix.SYS_MMAP, addr, uintptr(length), uintptr(prot), uintptr(flags), uintptr(fd), uintptr(offset))
+	if errno != 0 {
+		return nil, errno
+	}
+
+	return bytesAt(r, length), nil
+}
+
+func sysMunmap(b []byte) error {
+	_, _, errno := unix.Syscall(unix.SYS_MUNMAP, addrOf(b), uintptr(len(b)), 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func sysMremap(b []byte, newLength int) ([]byte, error) {
+	r, _, errno := unix.Syscall6(unix.SYS_MREMAP, addrOf(b), uintptr(len(b)), uintptr(newLength), _MREMAP_MAYMOVE, 0, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+
+	return bytesAt(r, newLength), nil
+}
@@ -0,0 +1,84 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package mmap
+
+import (
+	"sync/atomic"
+
+	"golang.org/x/sys/unix"
+)
+
+// Truncate changes the length and capacity of the map as well as the size of
+// the backing file to exactly size bytes. Readers positioned beyond the new
+// end get io.EOF. With a shared header the map cannot shrink below the
+// capacity in the header, as other processes may still use it.
+func (m *Mmap) Truncate(size int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.truncate(size)
+}
+
+// ShrinkToFit gives back the capacity behind Len, rounded up to the page
+// size. Len itself is left alone.
+func (m *Mmap) ShrinkToFit() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrIsClosed
+	}
+
+	length := m.Len()
+	if size := alignPage(length); size < len(m.data) {
+		if err := m.truncate(size); err != nil {
+			return err
+		}
+		atomic.StoreInt64(&m.length, int64(length))
+	}
+
+	return nil
+}
+
+func (m *Mmap) truncate(size int) error {
+	if m.closed {
+		return ErrIsClosed
+	}
+
+	if size < 0 {
+		return ErrOverflow
+	}
+	if m.reserved != nil && size > m.reservation() {
+		return ErrReservationExceeded
+	}
+
+	if m.header {
+		if err := m.growShared(size, true); err != nil {
+			return err
+		}
+
+		atomic.StoreInt64(&m.length, int64(size))
+		return nil
+	}
+
+	if m.file != nil {
+		// a window into the middle of the file must not cut off what follows
+		if owns, err := m.ownsTail(); err != nil {
+			return err
+		} else if owns {
+			// the lock keeps everyone away from the pages beyond the new end
+			// of file until they are unmapped
+			if err := unix.Ftruncate(int(m.file.Fd()), m.offset()+int64(size)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := m.remap(size); err != nil {
+		return err
+	}
+
+	atomic.StoreInt64(&m.length, int64(size))
+	return nil
+}
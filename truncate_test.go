@@ -0,0 +1,218 @@
+package mmap
+
+import (
+	"io"
+	"io/ioutil"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/ImSingee/tt"
+)
+
+func TestTruncate(t *testing.T) {
+	t.Run("shrink", func(t *testing.T) {
+		mmap, err := New(NewReadWrite(""))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		_, err = mmap.WriteAt([]byte(HelloWorld), 0)
+		tt.AssertIsNotError(t, err)
+		tt.AssertIsNotError(t, mmap.EnsureCapacity(64*oneMB))
+
+		tt.AssertIsNotError(t, mmap.Truncate(5))
+		tt.AssertEqual(t, 5, mmap.Cap())
+		tt.AssertEqual(t, int64(5), fileSize(mmap.args.(*Args).File))
+
+		p, err := mmap.Bytes(0, LenOfHelloWorld)
+		tt.AssertEqual(t, io.EOF, err)
+		tt.AssertEqual(t, "Hello", string(p))
+	})
+
+	t.Run("shrink-without-mremap", func(t *testing.T) {
+		defer func(old bool) { canMremap = old }(canMremap)
+		canMremap = false
+
+		mmap, err := New(NewReadWrite(""))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		_, err = mmap.WriteAt([]byte(HelloWorld), 0)
+		tt.AssertIsNotError(t, err)
+
+		tt.AssertIsNotError(t, mmap.Truncate(5))
+		tt.AssertEqual(t, 5, mmap.Cap())
+
+		p, err := mmap.Bytes(0, LenOfHelloWorld)
+		tt.AssertEqual(t, io.EOF, err)
+		tt.AssertEqual(t, "Hello", string(p))
+	})
+
+	t.Run("reader-beyond-end", func(t *testing.T) {
+		mmap, err := New(NewReadWrite(""))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		_, err = mmap.WriteAt([]byte(HelloWorld), 0)
+		tt.AssertIsNotError(t, err)
+
+		r := mmap.ReaderAt(8)
+		tt.AssertIsNotError(t, mmap.Truncate(6))
+
+		n, err := r.Read(make([]byte, 4))
+		tt.AssertEqual(t, io.EOF, err)
+		tt.AssertEqual(t, 0, n)
+	})
+
+	t.Run("to-zero-and-grow-again", func(t *testing.T) {
+		mmap, err := New(NewReadWrite(""))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		tt.AssertIsNotError(t, mmap.Truncate(0))
+		tt.AssertEqual(t, 0, mmap.Cap())
+		tt.AssertFalse(t, mmap.IsClosed())
+		tt.AssertEqual(t, int64(0), fileSize(mmap.args.(*Args).File))
+
+		n, err := mmap.ReadAt(make([]byte, 1), 0)
+		tt.AssertEqual(t, io.EOF, err)
+		tt.AssertEqual(t, 0, n)
+
+		_, err = mmap.WriteAt([]byte(HelloWorld), 0)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, oneMB, mmap.Cap())
+
+		p, err := mmap.Bytes(0, LenOfHelloWorld)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, HelloWorld, string(p))
+	})
+
+	t.Run("extend", func(t *testing.T) {
+		f, err := newHelloWorldFile()
+		tt.AssertIsNotError(t, err)
+
+		mmap, err := New(NewReadWrite(f))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		tt.AssertIsNotError(t, mmap.Truncate(100))
+		tt.AssertEqual(t, 100, mmap.Cap())
+		tt.AssertEqual(t, int64(100), fileSize(f))
+	})
+
+	t.Run("readonly", func(t *testing.T) {
+		f, err := newHelloWorldFile()
+		tt.AssertIsNotError(t, err)
+
+		mmap, err := New(NewReadOnly(f))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		tt.AssertIsError(t, mmap.Truncate(5))
+		tt.AssertEqual(t, LenOfHelloWorld, mmap.Cap())
+
+		p, err := mmap.Bytes(0, LenOfHelloWorld)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, HelloWorld, string(p))
+	})
+
+	t.Run("reserved", func(t *testing.T) {
+		if runtime.GOOS != "linux" {
+			t.Skip("ReserveSize is only supported on linux")
+		}
+
+		args := NewReadWrite("")
+		args.ReserveSize = 64 * oneMB
+
+		mmap, err := New(args)
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		base := &mmap.data[0]
+		_, err = mmap.WriteAt([]byte(HelloWorld), 2*oneMB+oneMB/2)
+		tt.AssertIsNotError(t, err)
+
+		tt.AssertIsNotError(t, mmap.Truncate(oneMB+5))
+		tt.AssertEqual(t, oneMB+5, mmap.Cap())
+
+		_, err = mmap.WriteAt([]byte(HelloWorld), int64(3*oneMB-LenOfHelloWorld))
+		tt.AssertIsNotError(t, err)
+		tt.AssertTrue(t, base == &mmap.data[0])
+
+		tt.AssertEqual(t, 3*oneMB, mmap.Cap())
+
+		// the truncated tail comes back zeroed
+		p, err := mmap.Bytes(2*oneMB+oneMB/2, LenOfHelloWorld)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, make([]byte, LenOfHelloWorld), p)
+
+		tt.AssertEqual(t, ErrReservationExceeded, mmap.Truncate(65*oneMB))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		mmap, err := New(NewReadWrite(""))
+		tt.AssertIsNotError(t, err)
+
+		tt.AssertEqual(t, ErrOverflow, mmap.Truncate(-1))
+
+		closeMmap(t, mmap)
+		tt.AssertEqual(t, ErrIsClosed, mmap.Truncate(1))
+		tt.AssertEqual(t, ErrIsClosed, mmap.ShrinkToFit())
+	})
+}
+
+func TestShrinkToFit(t *testing.T) {
+	mmap, err := New(NewReadWrite(""))
+	tt.AssertIsNotError(t, err)
+	defer closeMmap(t, mmap)
+
+	_, err = mmap.WriteAt([]byte(HelloWorld), 3*oneMB+100)
+	tt.AssertIsNotError(t, err)
+	tt.AssertEqual(t, 4*oneMB, mmap.Cap())
+
+	tt.AssertIsNotError(t, mmap.ShrinkToFit())
+	tt.AssertEqual(t, alignPage(3*oneMB+100+LenOfHelloWorld), mmap.Cap())
+	tt.AssertEqual(t, 3*oneMB+100+LenOfHelloWorld, mmap.Len())
+	tt.AssertEqual(t, int64(mmap.Cap()), fileSize(mmap.args.(*Args).File))
+
+	p, err := ioutil.ReadFile(mmap.args.(*Args).File)
+	tt.AssertIsNotError(t, err)
+	tt.AssertEqual(t, HelloWorld, string(p[3*oneMB+100:3*oneMB+100+LenOfHelloWorld]))
+
+	// nothing more to give back
+	tt.AssertIsNotError(t, mmap.ShrinkToFit())
+	tt.AssertEqual(t, alignPage(3*oneMB+100+LenOfHelloWorld), mmap.Cap())
+}
+
+func TestTruncateConcurrentLen(t *testing.T) {
+	mmap, err := New(NewReadWrite(""))
+	tt.AssertIsNotError(t, err)
+	defer closeMmap(t, mmap)
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					_ = mmap.Len()
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 100; i++ {
+		tt.AssertIsNotError(t, mmap.Truncate(4096+i))
+		_, err := mmap.WriteAt([]byte(HelloWorld), 8192)
+		tt.AssertIsNotError(t, err)
+		tt.AssertIsNotError(t, mmap.ShrinkToFit())
+	}
+
+	close(done)
+	wg.Wait()
+}
@@ -0,0 +1,88 @@
+package mmap
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// WaitUint32 blocks while the word at off holds old, for at most timeout, and
+// reports whether it changed. A negative timeout waits without limit. The word
+// must be 4 byte aligned, as for the atomic operations.
+func (m *Mmap) WaitUint32(off int64, old uint32, timeout time.Duration) (changed bool, err error) {
+	if timeout < 0 {
+		err = m.WaitUint32Context(context.Background(), off, old)
+		return err == nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	switch err = m.WaitUint32Context(ctx, off, old); err {
+	case nil:
+		return true, nil
+	case context.DeadlineExceeded:
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// WaitUint32Context blocks while the word at off holds old, until ctx is
+// done. Like the locks, it lets go of the map every slice, that is when it
+// notices that ctx is canceled.
+func (m *Mmap) WaitUint32Context(ctx context.Context, off int64, old uint32) error {
+	for {
+		slice := waitSlice
+		if deadline, ok := ctx.Deadline(); ok {
+			if until := time.Until(deadline); until < slice {
+				slice = until
+			}
+		}
+
+		changed, err := m.waitWord(off, old, slice)
+		if err != nil || changed {
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if slice <= 0 {
+			return context.DeadlineExceeded
+		}
+	}
+}
+
+// waitWord sleeps for at most d while the word at off holds old and reports
+// whether it changed.
+func (m *Mmap) waitWord(off int64, old uint32, d time.Duration) (changed bool, err error) {
+	p, err := m.word(off, 4)
+	if err != nil {
+		return false, err
+	}
+	defer m.mu.RUnlock()
+
+	w := (*uint32)(p)
+	if atomic.LoadUint32(w) == old && d > 0 {
+		if err := futexWait(w, old, d); err != nil {
+			return false, err
+		}
+	}
+
+	return atomic.LoadUint32(w) != old, nil
+}
+
+// NotifyUint32 wakes at most n waiters on the word at off, in any process,
+// and returns how many it woke. The word has to be changed before, e.g. with
+// AtomicAddUint32, or the waiters go back to sleep. Waiters on darwin poll and
+// are never counted.
+func (m *Mmap) NotifyUint32(off int64, n int) (woken int, err error) {
+	p, err := m.word(off, 4)
+	if err != nil {
+		return 0, err
+	}
+	defer m.mu.RUnlock()
+
+	return futexWake((*uint32)(p), n)
+}
@@ -0,0 +1,125 @@
+package mmap
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ImSingee/tt"
+)
+
+func TestWaitUint32(t *testing.T) {
+	t.Run("process", func(t *testing.T) {
+		mmap, file := newIpcMmap(t)
+		defer closeMmap(t, mmap)
+
+		cmd := runHelper("notify", file)
+		tt.AssertIsNotError(t, cmd.Start())
+
+		changed, err := mmap.WaitUint32(ipcFlag, 0, 10*time.Second)
+		tt.AssertIsNotError(t, err)
+		tt.AssertTrue(t, changed)
+		tt.AssertIsNotError(t, cmd.Wait())
+
+		v, err := mmap.AtomicLoadUint32(ipcFlag)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, uint32(1), v)
+	})
+
+	t.Run("notify", func(t *testing.T) {
+		mmap, _ := newIpcMmap(t)
+		defer closeMmap(t, mmap)
+
+		done := make(chan error, 1)
+		go func() {
+			done <- mmap.WaitUint32Context(context.Background(), ipcFlag, 0)
+		}()
+
+		time.Sleep(10 * time.Millisecond)
+		_, err := mmap.AtomicAddUint32(ipcFlag, 1)
+		tt.AssertIsNotError(t, err)
+		_, err = mmap.NotifyUint32(ipcFlag, 1)
+		tt.AssertIsNotError(t, err)
+
+		tt.AssertIsNotError(t, <-done)
+	})
+
+	t.Run("already-changed", func(t *testing.T) {
+		mmap, _ := newIpcMmap(t)
+		defer closeMmap(t, mmap)
+
+		changed, err := mmap.WaitUint32(ipcFlag, 1, time.Hour)
+		tt.AssertIsNotError(t, err)
+		tt.AssertTrue(t, changed)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		mmap, _ := newIpcMmap(t)
+		defer closeMmap(t, mmap)
+
+		start := time.Now()
+		changed, err := mmap.WaitUint32(ipcFlag, 0, 50*time.Millisecond)
+		tt.AssertIsNotError(t, err)
+		tt.AssertFalse(t, changed)
+		tt.AssertTrue(t, time.Since(start) >= 50*time.Millisecond)
+
+		changed, err = mmap.WaitUint32(ipcFlag, 0, 0)
+		tt.AssertIsNotError(t, err)
+		tt.AssertFalse(t, changed)
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		mmap, _ := newIpcMmap(t)
+		defer closeMmap(t, mmap)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(10*time.Millisecond, cancel)
+
+		err := mmap.WaitUint32Context(ctx, ipcFlag, 0)
+		tt.AssertEqual(t, context.Canceled, err)
+	})
+
+	t.Run("growth-while-waiting", func(t *testing.T) {
+		mmap, _ := newIpcMmap(t)
+		defer closeMmap(t, mmap)
+
+		done := make(chan error, 1)
+		go func() {
+			done <- mmap.WaitUint32Context(context.Background(), ipcFlag, 0)
+		}()
+
+		time.Sleep(10 * time.Millisecond)
+		for i := 1; i <= 4; i++ {
+			_, err := mmap.WriteAt([]byte{1}, int64(i*oneMB))
+			tt.AssertIsNotError(t, err)
+		}
+
+		tt.AssertIsNotError(t, mmap.AtomicStoreUint32(ipcFlag, 1))
+		_, err := mmap.NotifyUint32(ipcFlag, wakeAll)
+		tt.AssertIsNotError(t, err)
+		tt.AssertIsNotError(t, <-done)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		mmap, _ := newIpcMmap(t)
+		defer closeMmap(t, mmap)
+
+		_, err := mmap.WaitUint32(2, 0, 0)
+		tt.AssertEqual(t, ErrUnaligned, err)
+		_, err = mmap.NotifyUint32(-4, 1)
+		tt.AssertEqual(t, ErrOverflow, err)
+
+		changed, err := mmap.WaitUint32(2, 0, -1)
+		tt.AssertEqual(t, ErrUnaligned, err)
+		tt.AssertFalse(t, changed)
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		mmap, _ := newIpcMmap(t)
+		closeMmap(t, mmap)
+
+		changed, err := mmap.WaitUint32(0, 0, -1)
+		tt.AssertEqual(t, ErrIsClosed, err)
+		tt.AssertFalse(t, changed)
+	})
+}
@@ -0,0 +1,282 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package mmap
+
+import (
+	"container/list"
+	"io"
+	"os"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+var (
+	_ io.ReaderAt = (*WindowedMmap)(nil)
+	_ io.WriterAt = (*WindowedMmap)(nil)
+)
+
+// WindowedMmap gives access to files larger than what can (or should) be
+// mapped at once. It maps page aligned windows of the file on demand and
+// unmaps the least recently used ones to keep within a budget of mapped
+// bytes. Accesses spanning several windows are handled transparently.
+//
+// It is safe for concurrent use. Windows in use by an access are never
+// unmapped, so the budget may be exceeded temporarily under contention.
+type WindowedMmap struct {
+	file       *os.File
+	prot       int
+	windowSize int
+	maxWindows int
+
+	mu      sync.Mutex
+	size    int64
+	windows map[int64]*list.Element // of *window
+	lru     *list.List
+	closed  bool
+}
+
+type window struct {
+	index int64
+	data  []byte
+	refs  int
+}
+
+// NewWindowed opens args.File (InitLength is ignored) for windowed access
+// with windows of windowSize bytes, rounded up to the page size, keeping at
+// most budget bytes (but at least one window) mapped.
+//
+// Private mappings are not supported, their changes would be lost whenever a
+// window is evicted.
+func NewWindowed(args *Args, windowSize int, budget int) (*WindowedMmap, error) {
+	if args.Private {
+		return nil, ErrNotSupported
+	}
+	if windowSize <= 0 || budget < 0 {
+		return nil, ErrOverflow
+	}
+
+	if err := args.Clean(); err != nil {
+		return nil, err
+	}
+
+	f, err := args.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	w := &WindowedMmap{
+		file:       f,
+		prot:       args.Prot(),
+		windowSize: alignPage(windowSize),
+		size:       stat.Size(),
+		windows:    make(map[int64]*list.Element),
+		lru:        list.New(),
+	}
+
+	w.maxWindows = budget / w.windowSize
+	if w.maxWindows < 1 {
+		w.maxWindows = 1
+	}
+
+	return w, nil
+}
+
+// Size returns the size of the file
+func (w *WindowedMmap) Size() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.size
+}
+
+func (w *WindowedMmap) ReadAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, ErrOverflow
+	}
+
+	for n < len(p) {
+		win, end, err := w.acquire(off)
+		if err != nil {
+			return n, err
+		}
+		if off >= end {
+			w.release(win)
+			return n, io.EOF
+		}
+
+		start := off - win.index*int64(w.windowSize)
+		c := copy(p[n:], win.data[start:end-win.index*int64(w.windowSize)])
+		w.release(win)
+
+		n += c
+		off += int64(c)
+	}
+
+	return n, nil
+}
+
+// WriteAt writes p at off, extending the file if needed.
+func (w *WindowedMmap) WriteAt(p []byte, off int64) (n int, err error) {
+	if w.prot&unix.PROT_WRITE == 0 {
+		return 0, ErrReadOnly
+	}
+	if off < 0 {
+		return 0, ErrOverflow
+	}
+
+	if err := w.extend(off + int64(len(p))); err != nil {
+		return 0, err
+	}
+
+	for n < len(p) {
+		win, _, err := w.acquire(off)
+		if err != nil {
+			return n, err
+		}
+
+		c := copy(win.data[off-win.index*int64(w.windowSize):], p[n:])
+		w.release(win)
+
+		n += c
+		off += int64(c)
+	}
+
+	return n, nil
+}
+
+// Flush writes the dirty pages of all mapped windows back to the file.
+func (w *WindowedMmap) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return ErrIsClosed
+	}
+
+	for e := w.lru.Front(); e != nil; e = e.Next() {
+		if err := unix.Msync(e.Value.(*window).data, unix.MS_SYNC); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close unmaps all windows and closes the file. Windows still in use by a
+// concurrent access are unmapped once that access is done.
+func (w *WindowedMmap) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	var err error
+	for e := w.lru.Front(); e != nil; {
+		next := e.Next()
+		if win := e.Value.(*window); win.refs == 0 {
+			if uerr := w.unmap(e); err == nil {
+				err = uerr
+			}
+		}
+		e = next
+	}
+
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (w *WindowedMmap) extend(end int64) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return ErrIsClosed
+	}
+
+	if end > w.size {
+		if err := unix.Ftruncate(int(w.file.Fd()), end); err != nil {
+			return err
+		}
+		w.size = end
+	}
+
+	return nil
+}
+
+// acquire returns the window holding off, mapping it if needed, together
+// with the end of the file data it holds (as an absolute offset).
+func (w *WindowedMmap) acquire(off int64) (*window, int64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil, 0, ErrIsClosed
+	}
+
+	index := off / int64(w.windowSize)
+	end := (index + 1) * int64(w.windowSize)
+	if end > w.size {
+		end = w.size
+	}
+
+	if e, ok := w.windows[index]; ok {
+		win := e.Value.(*window)
+		win.refs++
+		w.lru.MoveToFront(e)
+		return win, end, nil
+	}
+
+	data, err := sysMmap(0, w.windowSize, w.prot, unix.MAP_SHARED, int(w.file.Fd()), index*int64(w.windowSize))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	win := &window{index: index, data: data, refs: 1}
+	w.windows[index] = w.lru.PushFront(win)
+	w.evict()
+
+	return win, end, nil
+}
+
+func (w *WindowedMmap) release(win *window) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	win.refs--
+	if w.closed && win.refs == 0 {
+		_ = w.unmap(w.windows[win.index])
+		return
+	}
+
+	w.evict()
+}
+
+// evict unmaps the least recently used idle windows beyond the budget
+func (w *WindowedMmap) evict() {
+	for e := w.lru.Back(); e != nil && w.lru.Len() > w.maxWindows; {
+		prev := e.Prev()
+		if e.Value.(*window).refs == 0 {
+			_ = w.unmap(e)
+		}
+		e = prev
+	}
+}
+
+func (w *WindowedMmap) unmap(e *list.Element) error {
+	win := w.lru.Remove(e).(*window)
+	delete(w.windows, win.index)
+	return sysMunmap(win.data)
+}
@@ -0,0 +1,169 @@
+package mmap
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	"github.com/ImSingee/tt"
+)
+
+func newPatternFile(t *testing.T, size int) (string, []byte) {
+	t.Helper()
+
+	content := make([]byte, size)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+
+	f, err := ioutil.TempFile("", "")
+	tt.AssertIsNotError(t, err)
+	defer f.Close()
+
+	_, err = f.Write(content)
+	tt.AssertIsNotError(t, err)
+
+	return f.Name(), content
+}
+
+func TestWindowed(t *testing.T) {
+	windowSize := 4 * pageSize
+
+	t.Run("read-across-windows", func(t *testing.T) {
+		f, content := newPatternFile(t, 10*windowSize+123)
+
+		w, err := NewWindowed(NewReadOnly(f), windowSize, 2*windowSize)
+		tt.AssertIsNotError(t, err)
+		defer func() { tt.AssertIsNotError(t, w.Close()) }()
+
+		tt.AssertEqual(t, int64(len(content)), w.Size())
+
+		p := make([]byte, 3*windowSize)
+		n, err := w.ReadAt(p, int64(windowSize/2))
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, len(p), n)
+		tt.AssertEqual(t, content[windowSize/2:windowSize/2+len(p)], p)
+		tt.AssertTrue(t, w.lru.Len() <= 2)
+
+		all, err := ioutil.ReadAll(io.NewSectionReader(w, 0, w.Size()))
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, content, all)
+		tt.AssertTrue(t, w.lru.Len() <= 2)
+
+		n, err = w.ReadAt(p, int64(len(content)-10))
+		tt.AssertEqual(t, io.EOF, err)
+		tt.AssertEqual(t, 10, n)
+		tt.AssertEqual(t, content[len(content)-10:], p[:10])
+
+		n, err = w.ReadAt(p, int64(len(content)+10))
+		tt.AssertEqual(t, io.EOF, err)
+		tt.AssertEqual(t, 0, n)
+	})
+
+	t.Run("write-and-extend", func(t *testing.T) {
+		f, content := newPatternFile(t, 2*windowSize+5)
+
+		w, err := NewWindowed(NewReadWrite(f), windowSize, 0)
+		tt.AssertIsNotError(t, err)
+
+		data := bytes.Repeat([]byte(HelloWorld), windowSize/LenOfHelloWorld+1)
+
+		// spans the boundary between the first two windows
+		_, err = w.WriteAt(data, int64(windowSize-7))
+		tt.AssertIsNotError(t, err)
+		copy(content[windowSize-7:], data)
+
+		// beyond the end of file
+		_, err = w.WriteAt(data, int64(5*windowSize))
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, int64(5*windowSize+len(data)), w.Size())
+		content = append(content, make([]byte, 5*windowSize-len(content))...)
+		content = append(content, data...)
+
+		tt.AssertEqual(t, 1, w.lru.Len())
+		tt.AssertIsNotError(t, w.Flush())
+		tt.AssertIsNotError(t, w.Close())
+
+		p, err := ioutil.ReadFile(f)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, content, p)
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		f, content := newPatternFile(t, 16*windowSize)
+
+		w, err := NewWindowed(NewReadWrite(f), windowSize, 3*windowSize)
+		tt.AssertIsNotError(t, err)
+		defer func() { tt.AssertIsNotError(t, w.Close()) }()
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 8)
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+
+				p := make([]byte, windowSize+100)
+				for i := 0; i < 200; i++ {
+					off := ((g*31 + i*17) % 15) * windowSize
+					if _, err := w.ReadAt(p, int64(off)); err != nil {
+						errs <- err
+						return
+					}
+					if !bytes.Equal(p, content[off:off+len(p)]) {
+						errs <- io.ErrUnexpectedEOF
+						return
+					}
+				}
+			}(g)
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			tt.AssertIsNotError(t, err)
+		}
+		tt.AssertTrue(t, w.lru.Len() <= 3)
+	})
+
+	t.Run("readonly", func(t *testing.T) {
+		f, _ := newPatternFile(t, 100)
+
+		w, err := NewWindowed(NewReadOnly(f), windowSize, windowSize)
+		tt.AssertIsNotError(t, err)
+		defer func() { tt.AssertIsNotError(t, w.Close()) }()
+
+		n, err := w.WriteAt([]byte{1}, 0)
+		tt.AssertEqual(t, ErrReadOnly, err)
+		tt.AssertEqual(t, 0, n)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		f, _ := newPatternFile(t, 100)
+
+		args := NewReadWrite(f)
+		args.Private = true
+		_, err := NewWindowed(args, windowSize, windowSize)
+		tt.AssertEqual(t, ErrNotSupported, err)
+
+		_, err = NewWindowed(NewReadWrite(f), 0, windowSize)
+		tt.AssertEqual(t, ErrOverflow, err)
+	})
+
+	t.Run("after-close", func(t *testing.T) {
+		f, _ := newPatternFile(t, 100)
+
+		w, err := NewWindowed(NewReadWrite(f), windowSize, windowSize)
+		tt.AssertIsNotError(t, err)
+		tt.AssertIsNotError(t, w.Close())
+		tt.AssertIsNotError(t, w.Close())
+
+		_, err = w.ReadAt(make([]byte, 1), 0)
+		tt.AssertEqual(t, ErrIsClosed, err)
+		_, err = w.WriteAt(make([]byte, 1), 0)
+		tt.AssertEqual(t, ErrIsClosed, err)
+		tt.AssertEqual(t, ErrIsClosed, w.Flush())
+	})
+}
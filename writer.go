@@ -2,52 +2,129 @@ package mmap
 
 import (
 	"io"
+	"os"
+	"unicode/utf8"
 	"unsafe"
 )
 
-var _ io.WriterAt = (*Mmap)(nil)
+var (
+	_ io.WriterAt   = (*Mmap)(nil)
+	_ io.ReaderFrom = (*Mmap)(nil)
+)
 
 func (m *Mmap) WriteAt(p []byte, off int64) (n int, err error) {
-	if m.closed {
-		return 0, ErrIsClosed
+	if m.readOnly() {
+		return 0, ErrReadOnly
 	}
 
 	end := int(off) + len(p)
-	if err = m.EnsureCapacity(end); err != nil {
+	if err = m.rlock(end); err != nil {
 		return 0, err
 	}
+	defer m.mu.RUnlock()
+
+	n = copy(m.data[off:end], p)
+	m.extend(end)
+	return n, nil
+}
+
+// copyChunk is the most WriteTo copies through a buffer and ReadFromAt reads
+// at a time, and the least room ReadFromAt makes before every read.
+const copyChunk = 32 * 1024
+
+// ReadFrom writes everything read from r until io.EOF to the start of the map.
+func (m *Mmap) ReadFrom(r io.Reader) (n int64, err error) {
+	return m.ReadFromAt(0, r)
+}
+
+// ReadFromAt writes everything read from r until io.EOF to the map from off
+// on. r reads straight into the mapping, at most copyChunk bytes at a time,
+// and the map grows ahead of it in the steps the Grower picks. The map is held
+// for one read at a time, so a read that blocks holds up growth and Close
+// until it returns, and r must not use the map itself. On Linux a file or pipe
+// is copied within the kernel where possible, which waits for it without
+// holding the map.
+func (m *Mmap) ReadFromAt(off int64, r io.Reader) (n int64, err error) {
+	if off < 0 {
+		return 0, ErrOverflow
+	}
+	if m.IsClosed() {
+		return 0, ErrIsClosed
+	}
+	if m.readOnly() {
+		return 0, ErrReadOnly
+	}
+
+	if f, ok := r.(*os.File); ok {
+		if n, handled, err := m.readFromFile(off, f); handled {
+			return n, err
+		}
+	}
 
-	return copy(m.data[off:end], p), nil
+	for {
+		pos := int(off + n)
+		if err = m.rlock(pos + copyChunk); err != nil {
+			return n, err
+		}
+
+		nr, er := r.Read(m.data[pos : pos+copyChunk])
+		if nr > 0 {
+			m.extend(pos + nr)
+			n += int64(nr)
+		}
+		m.mu.RUnlock()
+
+		if er == io.EOF {
+			return n, nil
+		}
+		if er != nil {
+			return n, er
+		}
+	}
 }
 
 func (m *Mmap) WriterAt(off int64) Writer {
-	return &mmapWriter{m, off}
+	return &mmapWriter{m: m, pos: off}
 }
 
 func (m *Mmap) Copy(srcPos, dstPos int64, length int) error {
-	if m.closed {
+	if m.IsClosed() {
 		return ErrIsClosed
 	}
 
+	if m.readOnly() {
+		return ErrReadOnly
+	}
+
 	if srcPos == dstPos {
 		return nil
 	}
 
-	if srcPos+int64(length) > int64(len(m.data)) {
+	if srcPos+int64(length) > int64(m.Cap()) {
 		return ErrOverflow
 	}
 
-	if err := m.EnsureCapacity(int(dstPos) + length); err != nil {
+	if err := m.rlock(int(dstPos) + length); err != nil {
 		return err
 	}
+	defer m.mu.RUnlock()
 
 	copy(m.data[dstPos:], m.data[srcPos:srcPos+int64(length)])
+	m.extend(int(dstPos) + length)
 	return nil
 }
 
+// Writer writes the map sequentially from its position, growing it as
+// needed.
 type Writer interface {
-	io.Writer
+	io.WriteSeeker
 	io.StringWriter
+	io.ByteWriter
+	io.ReaderFrom
+	WriteRune(r rune) (n int, err error)
+
+	// Pos returns the current position, relative to the start of the map
+	Pos() int64
 }
 
 type mmapWriter struct {
@@ -69,3 +146,37 @@ func (w *mmapWriter) WriteString(s string) (n int, err error) {
 		}{s, len(s)},
 	)))
 }
+
+func (w *mmapWriter) WriteByte(c byte) error {
+	_, err := w.Write([]byte{c})
+	return err
+}
+
+func (w *mmapWriter) WriteRune(r rune) (n int, err error) {
+	if r < utf8.RuneSelf {
+		return 1, w.WriteByte(byte(r))
+	}
+
+	var p [utf8.UTFMax]byte
+	return w.Write(p[:utf8.EncodeRune(p[:], r)])
+}
+
+func (w *mmapWriter) ReadFrom(r io.Reader) (n int64, err error) {
+	n, err = w.m.ReadFromAt(w.pos, r)
+	w.pos += n
+	return
+}
+
+func (w *mmapWriter) Seek(offset int64, whence int) (int64, error) {
+	pos, err := seek(w.m, w.pos, offset, whence)
+	if err != nil {
+		return w.pos, err
+	}
+
+	w.pos = pos
+	return pos, nil
+}
+
+func (w *mmapWriter) Pos() int64 {
+	return w.pos
+}
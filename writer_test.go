@@ -1,6 +1,8 @@
 package mmap
 
 import (
+	"errors"
+	"io"
 	"strings"
 	"testing"
 
@@ -20,6 +22,25 @@ func TestWrite(t *testing.T) {
 		tt.AssertEqual(t, ErrIsClosed, err)
 		tt.AssertEqual(t, 0, n)
 	})
+
+	t.Run("read-only", func(t *testing.T) {
+		f, err := newHelloWorldFile()
+		tt.AssertIsNotError(t, err)
+
+		mmap, err := New(NewReadOnly(f))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		n, err := mmap.WriteAt([]byte{6}, 1)
+		tt.AssertEqual(t, ErrReadOnly, err)
+		tt.AssertEqual(t, 0, n)
+		_, err = mmap.WriterAt(0).WriteString(HelloWorld)
+		tt.AssertEqual(t, ErrReadOnly, err)
+		_, err = mmap.ReadFromAt(0, strings.NewReader(HelloWorld))
+		tt.AssertEqual(t, ErrReadOnly, err)
+		tt.AssertEqual(t, ErrReadOnly, mmap.Copy(0, 1, 2))
+		tt.AssertEqual(t, HelloWorld, string(mmap.data))
+	})
 }
 
 func TestCopy(t *testing.T) {
@@ -166,4 +187,190 @@ func TestWriter(t *testing.T) {
 
 		tt.AssertEqual(t, expect, mmap.data)
 	})
+	t.Run("scalars", func(t *testing.T) {
+		mmap, err := New(NewReadWrite(""))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		w := mmap.WriterAt(0)
+		tt.AssertIsNotError(t, w.WriteByte('a'))
+
+		n, err := w.WriteRune('é')
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, 2, n)
+		n, err = w.WriteRune('!')
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, 1, n)
+
+		tt.AssertEqual(t, int64(4), w.Pos())
+		tt.AssertEqual(t, 4, mmap.Len())
+		tt.AssertEqual(t, "aé!", string(mmap.data[:4]))
+	})
+
+	t.Run("seek", func(t *testing.T) {
+		f, err := newHelloWorldFile()
+		tt.AssertIsNotError(t, err)
+
+		mmap, err := New(NewReadWrite(f))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		w := mmap.WriterAt(0)
+		pos, err := w.Seek(-1, io.SeekEnd)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, int64(LenOfHelloWorld-1), pos)
+
+		_, err = w.WriteString("?")
+		tt.AssertIsNotError(t, err)
+
+		// SeekEnd appends
+		_, err = w.Seek(0, io.SeekEnd)
+		tt.AssertIsNotError(t, err)
+		_, err = w.WriteString("!!")
+		tt.AssertIsNotError(t, err)
+
+		tt.AssertEqual(t, LenOfHelloWorld+2, mmap.Len())
+		tt.AssertEqual(t, "Hello world?!!", string(mmap.data[:mmap.Len()]))
+
+		_, err = w.Seek(-1, io.SeekStart)
+		tt.AssertEqual(t, errNegative, err)
+		tt.AssertEqual(t, int64(LenOfHelloWorld+2), w.Pos())
+	})
+
+	t.Run("read-from", func(t *testing.T) {
+		mmap, err := New(NewReadWrite(""))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		data := strings.Repeat("ABCDEFGHIJKLMNOPQRSTUVWXYZ", 100000)
+
+		w := mmap.WriterAt(10)
+		n, err := w.ReadFrom(strings.NewReader(data))
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, int64(len(data)), n)
+		tt.AssertEqual(t, int64(10+len(data)), w.Pos())
+		tt.AssertEqual(t, 10+len(data), mmap.Len())
+		tt.AssertEqual(t, data, string(mmap.data[10:mmap.Len()]))
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		mmap, err := New(NewReadWrite(""))
+		tt.AssertIsNotError(t, err)
+		closeMmap(t, mmap)
+
+		w := mmap.WriterAt(0)
+		tt.AssertEqual(t, ErrIsClosed, w.WriteByte('a'))
+		_, err = w.WriteRune('é')
+		tt.AssertEqual(t, ErrIsClosed, err)
+		_, err = w.ReadFrom(strings.NewReader(HelloWorld))
+		tt.AssertEqual(t, ErrIsClosed, err)
+		tt.AssertEqual(t, int64(0), w.Pos())
+	})
+}
+
+func TestReadFrom(t *testing.T) {
+	t.Run("grow", func(t *testing.T) {
+		f, err := newHelloWorldFile()
+		tt.AssertIsNotError(t, err)
+
+		mmap, err := New(NewReadWrite(f))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		data := strings.Repeat("ABCDEFGHIJKLMNOPQRSTUVWXYZ", 100000)
+
+		n, err := mmap.ReadFromAt(6, strings.NewReader(data))
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, int64(len(data)), n)
+		tt.AssertEqual(t, 6+len(data), mmap.Len())
+		tt.AssertEqual(t, "Hello "+data, string(mmap.data[:mmap.Len()]))
+	})
+
+	t.Run("at-start", func(t *testing.T) {
+		f, err := newHelloWorldFile()
+		tt.AssertIsNotError(t, err)
+
+		mmap, err := New(NewReadWrite(f))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		n, err := mmap.ReadFrom(strings.NewReader("Howdy"))
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, int64(5), n)
+		tt.AssertEqual(t, LenOfHelloWorld, mmap.Len())
+		tt.AssertEqual(t, "Howdy world!", string(mmap.data[:mmap.Len()]))
+	})
+
+	t.Run("reader-error", func(t *testing.T) {
+		mmap, err := New(NewReadWrite(""))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		r := io.MultiReader(strings.NewReader(HelloWorld), iotestErrReader{})
+		n, err := mmap.ReadFrom(r)
+		tt.AssertEqual(t, errTestRead, err)
+		tt.AssertEqual(t, int64(LenOfHelloWorld), n)
+		tt.AssertEqual(t, LenOfHelloWorld, mmap.Len())
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		mmap, err := New(NewReadWrite(""))
+		tt.AssertIsNotError(t, err)
+		closeMmap(t, mmap)
+
+		n, err := mmap.ReadFrom(strings.NewReader(HelloWorld))
+		tt.AssertEqual(t, ErrIsClosed, err)
+		tt.AssertEqual(t, int64(0), n)
+	})
+
+	t.Run("negative", func(t *testing.T) {
+		mmap, err := New(NewReadWrite(""))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		_, err = mmap.ReadFromAt(-1, strings.NewReader(HelloWorld))
+		tt.AssertEqual(t, ErrOverflow, err)
+	})
+
+	t.Run("into-map", func(t *testing.T) {
+		mmap, err := New(NewReadWrite(""))
+		tt.AssertIsNotError(t, err)
+		defer closeMmap(t, mmap)
+
+		grown := 0
+		mmap.ChangeGrowPolicy(func(current int, atLeast int) int {
+			grown++
+			return DefaultGrowPolicy(current, atLeast)
+		})
+
+		// every read lands in the mapping itself, growing it in large steps
+		data := strings.Repeat("ABCDEFGHIJKLMNOPQRSTUVWXYZ", 200000)
+		src := strings.NewReader(data)
+		r := readerFunc(func(p []byte) (int, error) {
+			if len(p) == 0 || &p[0] != &mmap.data[mmap.Len()] {
+				return 0, errTestRead
+			}
+			return src.Read(p)
+		})
+
+		n, err := mmap.ReadFrom(r)
+		tt.AssertIsNotError(t, err)
+		tt.AssertEqual(t, int64(len(data)), n)
+		tt.AssertEqual(t, data, string(mmap.data[:mmap.Len()]))
+		tt.AssertEqual(t, 3, grown)
+	})
+}
+
+var errTestRead = errors.New("test read error")
+
+type iotestErrReader struct{}
+
+func (iotestErrReader) Read([]byte) (int, error) {
+	return 0, errTestRead
+}
+
+type readerFunc func(p []byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) {
+	return f(p)
 }
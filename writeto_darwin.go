@@ -0,0 +1,9 @@
+package mmap
+
+import "io"
+
+// darwin only has sendfile to sockets with different semantics, WriteTo
+// always copies from the mapping
+func (m *Mmap) sendTo(w io.Writer, start, end int64) (int64, bool, error) {
+	return 0, false, nil
+}
@@ -0,0 +1,81 @@
+package mmap
+
+import (
+	"io"
+	"net"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// maxSendfile is the most a single sendfile call may transfer
+const maxSendfile = 1 << 30
+
+// sendTo sends data[start:end] from the backing file to a file or socket w
+// with sendfile, which reads the same page cache the mapping shares. It
+// reports whether it handled the transfer, if not the first n bytes have been
+// sent and the rest is up to the caller.
+func (m *Mmap) sendTo(w io.Writer, start, end int64) (n int64, handled bool, err error) {
+	if start >= end || m.anonymous() || m.args.Flags()&unix.MAP_SHARED == 0 {
+		return 0, false, nil
+	}
+
+	var conn syscall.Conn
+	switch w := w.(type) {
+	case *os.File:
+		conn = w
+	case *net.TCPConn:
+		conn = w
+	case *net.UnixConn:
+		conn = w
+	default:
+		return 0, false, nil
+	}
+
+	rc, err := conn.SyscallConn()
+	if err != nil {
+		return 0, false, nil
+	}
+
+	src, err := m.dupFd()
+	if err != nil {
+		return 0, true, err
+	}
+	defer unix.Close(src)
+
+	off := m.offset() + start
+	remain := end - start
+	for remain > 0 {
+		var ns int
+		chunk := remain
+		if chunk > maxSendfile {
+			chunk = maxSendfile
+		}
+
+		we := rc.Write(func(fd uintptr) bool {
+			if ns, err = unix.Sendfile(int(fd), src, &off, int(chunk)); ns < 0 {
+				ns = 0
+			}
+			return err != unix.EAGAIN
+		})
+		n += int64(ns)
+		remain -= int64(ns)
+
+		if err == nil {
+			err = we
+		}
+		if err != nil {
+			if canFallBack(err) {
+				return n, false, nil
+			}
+			return n, true, err
+		}
+		if ns == 0 {
+			// the file is shorter than the mapping, let the caller copy
+			return n, false, nil
+		}
+	}
+
+	return n, true, nil
+}